@@ -8,8 +8,10 @@ This file is subject to the license terms contained in the license file that is
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
 )
@@ -21,10 +23,14 @@ var AssetTypes = map[string]string{
 }
 
 var (
-	// AssetDataTypes maps dataType --> ID
+	// AssetDataTypes maps dataType --> ID. It is shared by concurrent imports, so it is accessed with dataTypesLock.
 	AssetDataTypes map[string]int
-	// AssetDataTypeIDs maps ID --> dataType
+	// AssetDataTypeIDs maps ID --> dataType. It is shared by concurrent imports, so it is accessed with dataTypesLock.
 	AssetDataTypeIDs map[int]string
+
+	dataTypesLock sync.RWMutex
+	// locks of data type names, so concurrent imports do not create the same data type twice
+	dataTypeLocks = make(map[string]*sync.Mutex)
 )
 
 func init() {
@@ -32,6 +38,46 @@ func init() {
 	AssetDataTypeIDs = make(map[int]string)
 }
 
+// returns cached ID of a data type, or 0 if it is not cached
+func dataTypeID(dataType string) int {
+	dataTypesLock.RLock()
+	defer dataTypesLock.RUnlock()
+	return AssetDataTypes[dataType]
+}
+
+func cacheDataTypeID(dataType string, id int) {
+	dataTypesLock.Lock()
+	defer dataTypesLock.Unlock()
+	AssetDataTypes[dataType] = id
+}
+
+// returns cached name of a data type ID
+func cachedTypeRef(id int) (string, bool) {
+	dataTypesLock.RLock()
+	defer dataTypesLock.RUnlock()
+	name, ok := AssetDataTypeIDs[id]
+	return name, ok
+}
+
+func cacheTypeRef(id int, dataType string) {
+	dataTypesLock.Lock()
+	defer dataTypesLock.Unlock()
+	AssetDataTypeIDs[id] = dataType
+}
+
+// lock a data type name until the returned function is called
+func lockDataType(dataType string) func() {
+	dataTypesLock.Lock()
+	l, ok := dataTypeLocks[dataType]
+	if !ok {
+		l = &sync.Mutex{}
+		dataTypeLocks[dataType] = l
+	}
+	dataTypesLock.Unlock()
+	l.Lock()
+	return l.Unlock
+}
+
 func initializeAssetDataTypes() error {
 	basicTypes := []string{"string", "integer", "boolean", "array"}
 	for _, t := range basicTypes {
@@ -39,38 +85,20 @@ func initializeAssetDataTypes() error {
 		if err != nil {
 			return err
 		}
-		AssetDataTypes[t] = id
+		cacheDataTypeID(t, id)
 	}
 	return nil
 }
 
 func cleanAsyncAPISpec(spec interface{}) error {
-	if rid := getAsset(root); rid > 0 {
-		// remove root asset
-		fmt.Printf("cleanup asset %d -> %s\n", rid, root)
-		deleteAsset(rid)
+	refScope = root
+	if err := cleanRootAsset(root); err != nil {
+		return err
 	}
-
-	if components := getRef(spec, "#/components"); components != nil {
-		cm, ok := components.(map[string]interface{})
-		if !ok {
-			return errors.Errorf("components type %T is not a map", components)
-		}
-		for cat, val := range cm {
-			om, ok := val.(map[string]interface{})
-			if !ok {
-				continue
-			}
-			for k := range om {
-				if tid := getAssetDataType(fmt.Sprintf("#/components/%s/%s", cat, k)); tid > 0 {
-					// remove asset data types
-					fmt.Printf("cleanup data type %d -> %s\n", tid, k)
-					deleteAssetDataType(tid)
-				}
-			}
-		}
+	if err := cleanComponentDataTypes(spec); err != nil {
+		return err
 	}
-	return nil
+	return cleanAsyncAPI3DataTypes(spec)
 }
 
 func importAsyncAPISpec(spec map[string]interface{}) error {
@@ -78,45 +106,62 @@ func importAsyncAPISpec(spec map[string]interface{}) error {
 		return err
 	}
 
+	asyncAPIVersion = getString(spec, "#/asyncapi")
+	var errs specErrors
 	rid, err := createAsyncAPIAsset(spec)
 	if err != nil {
-		return nil
+		errs.add(err)
+		return errs.result()
 	}
 	if asyncapi, ok := spec["asyncapi"]; ok {
-		createSimpleAsset("asyncapi", asyncapi.(string), rid, "string")
+		_, err := createSimpleAsset("asyncapi", fmt.Sprintf("%v", asyncapi), rid, "string")
+		errs.add(err, "asyncapi")
 	}
-	if id, ok := spec["id"]; ok {
-		createSimpleAsset("id", id.(string), rid, "string")
+	for _, p := range []string{"id", "defaultContentType"} {
+		if v, ok := spec[p]; ok {
+			_, err := createSimpleAsset(p, fmt.Sprintf("%v", v), rid, "string")
+			errs.add(err, p)
+		}
 	}
 
+	var g importGroup
 	if info, ok := spec["info"]; ok {
-		createInfoAsset(info, rid)
+		g.run(func() error { return createInfoAsset(info, rid) }, "info")
 	}
 
 	if components, ok := spec["components"]; ok {
-		createComponentsAsset(components, rid)
+		g.run(func() error { return createComponentsAsset(components, rid) }, "components")
 	}
 
 	if servers, ok := spec["servers"]; ok {
-		createServersAsset(servers, rid)
+		g.run(func() error { return createServersAsset(servers, rid) }, "servers")
 	}
 
-	if channels, ok := spec["channels"].(map[string]interface{}); ok {
-		createChannelsAsset(channels, rid)
+	if channels, ok := spec["channels"]; ok {
+		if isAsyncAPI3() {
+			g.run(func() error { return createChannels3Asset(channels, rid) }, "channels")
+		} else {
+			g.run(func() error { return createChannelsAsset(channels, rid) }, "channels")
+		}
+	}
+
+	if operations, ok := spec["operations"]; ok {
+		g.run(func() error { return createOperationsAsset(operations, rid) }, "operations")
 	}
 
 	if tags, ok := spec["tags"]; ok {
-		createTagsAsset(tags, rid)
+		g.run(func() error { return createTagsAsset(tags, rid) }, "tags")
 	}
 
 	if externalDocs, ok := spec["externalDocs"]; ok {
-		createExternalDocsAsset(externalDocs, rid)
+		g.run(func() error { return createExternalDocsAsset(externalDocs, rid) }, "externalDocs")
 	}
-	return nil
+	errs.add(g.wait())
+	return errs.result()
 }
 
 func createAsyncAPIAsset(doc map[string]interface{}) (int, error) {
-	comment := extractExtraProperties(doc, []string{"id", "asyncapi", "info", "externalDocs", "tags", "components", "channels", "servers"})
+	comment := extractExtraProperties(doc, []string{"id", "asyncapi", "info", "defaultContentType", "externalDocs", "tags", "components", "channels", "operations", "servers"})
 	asset := Asset{
 		Name:                    root,
 		Label:                   root,
@@ -142,7 +187,7 @@ func createSimpleAsset(name, value string, parent int, dataType string) (int, er
 		IsDisabled:              false,
 	}
 	if dataType == "string" {
-		asset.AssetDataType = strconv.Itoa(AssetDataTypes["string"])
+		asset.AssetDataType = strconv.Itoa(dataTypeID("string"))
 	}
 	if parent > 0 {
 		asset.Parent = strconv.Itoa(parent)
@@ -150,8 +195,32 @@ func createSimpleAsset(name, value string, parent int, dataType string) (int, er
 	return createAsset(asset)
 }
 
+// create a simple asset of a string, or of JSON of a number, boolean or object value,
+// so the value is exported as the same JSON type
+func createValueAsset(name string, value interface{}, parent int) error {
+	if s, ok := value.(string); ok {
+		_, err := createSimpleAsset(name, s, parent, "string")
+		return err
+	}
+	return createJSONAsset(name, value, parent)
+}
+
+// create a simple asset that stores JSON of a value
+func createJSONAsset(name string, value interface{}, parent int) error {
+	data, err := json.MarshalIndent(value, "", "    ")
+	if err != nil {
+		return errors.Wrapf(err, "Failed to serialize %s", name)
+	}
+	_, err = createSimpleAsset(name, string(data), parent, "")
+	return err
+}
+
 func createInfoAsset(info interface{}, parent int) error {
-	comment := extractExtraProperties(info.(map[string]interface{}), []string{"description", "contact", "version"})
+	im, ok := info.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("info type %T is not a map", info)
+	}
+	comment := extractExtraProperties(im, []string{"description", "contact", "version", "tags", "externalDocs"})
 	asset := Asset{
 		Name:                    "info",
 		Label:                   "info",
@@ -166,17 +235,29 @@ func createInfoAsset(info interface{}, parent int) error {
 	if err != nil {
 		return err
 	}
-	createSimpleAsset("version", getString(info, "#/version"), pid, "string")
+
+	var errs specErrors
+	_, err = createSimpleAsset("version", getString(info, "#/version"), pid, "string")
+	errs.add(err, "version")
 	if contact := getRef(info, "#/contact"); contact != nil {
-		if value, err := json.MarshalIndent(contact, "", "    "); err == nil {
-			createSimpleAsset("contact", string(value), pid, "")
-		}
+		errs.add(createJSONAsset("contact", contact, pid), "contact")
 	}
-	return nil
+	// AsyncAPI 3.0 moves root tags and externalDocs into info
+	if tags := getRef(info, "#/tags"); tags != nil {
+		errs.add(createTagsAsset(tags, pid), "tags")
+	}
+	if externalDocs := getRef(info, "#/externalDocs"); externalDocs != nil {
+		errs.add(createExternalDocsAsset(externalDocs, pid), "externalDocs")
+	}
+	return errs.result()
 }
 
 func createExternalDocsAsset(docs interface{}, parent int) error {
-	comment := extractExtraProperties(docs.(map[string]interface{}), []string{"description"})
+	dm, ok := docs.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("externalDocs type %T is not a map", docs)
+	}
+	comment := extractExtraProperties(dm, []string{"description"})
 	asset := Asset{
 		Name:                    "externalDocs",
 		Label:                   "externalDocs",
@@ -192,12 +273,16 @@ func createExternalDocsAsset(docs interface{}, parent int) error {
 }
 
 func createTagsAsset(tags interface{}, parent int) error {
+	tagList, ok := tags.([]interface{})
+	if !ok {
+		return errors.Errorf("tags %T is not an array", tags)
+	}
 	asset := Asset{
 		Name:                    "tags",
 		Label:                   "tags",
 		Parent:                  strconv.Itoa(parent),
 		AssetType:               AssetTypes["JSON Element"],
-		AssetDataType:           strconv.Itoa(AssetDataTypes["array"]),
+		AssetDataType:           strconv.Itoa(dataTypeID("array")),
 		DataElementAutoAssigned: false,
 		IsDisabled:              false,
 	}
@@ -205,25 +290,41 @@ func createTagsAsset(tags interface{}, parent int) error {
 	if err != nil {
 		return err
 	}
-	if tagList, ok := tags.([]interface{}); ok {
-		for _, tag := range tagList {
-			name := getString(tag, "#/name")
-			asset := Asset{
-				Name:                    name,
-				Label:                   name,
-				Description:             getString(tag, "#/description"),
-				Parent:                  strconv.Itoa(pid),
-				AssetType:               AssetTypes["JSON Property"],
-				DataElementAutoAssigned: false,
-				IsDisabled:              false,
+
+	var errs specErrors
+	for i, tag := range tagList {
+		name := getString(tag, "#/name")
+		asset := Asset{
+			Name:                    name,
+			Label:                   name,
+			Description:             getString(tag, "#/description"),
+			Parent:                  strconv.Itoa(pid),
+			AssetType:               AssetTypes["JSON Property"],
+			DataElementAutoAssigned: false,
+			IsDisabled:              false,
+		}
+		// AsyncAPI 3.0 tag may refer to #/components/tags, and it is named by the component
+		if ref := getString(tag, "#/$ref"); len(ref) > 0 {
+			tid, err := setRef(ref)
+			if err != nil {
+				errs.add(err, strconv.Itoa(i))
+				continue
 			}
-			createAsset(asset)
+			asset.Name = ref[strings.LastIndex(ref, "/")+1:]
+			asset.Label = asset.Name
+			asset.AssetDataType = strconv.Itoa(tid)
 		}
+		_, err := createAsset(asset)
+		errs.add(err, strconv.Itoa(i))
 	}
-	return nil
+	return errs.result()
 }
 
 func createComponentsAsset(components interface{}, parent int) error {
+	cm, ok := components.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("components type %T is not a map", components)
+	}
 	asset := Asset{
 		Name:                    "components",
 		Label:                   "components",
@@ -236,55 +337,107 @@ func createComponentsAsset(components interface{}, parent int) error {
 	if err != nil {
 		return err
 	}
-	cm, ok := components.(map[string]interface{})
+
+	var g importGroup
+	for cat, list := range cm {
+		cat, list := cat, list
+		g.run(func() error { return createComponentCategoryAsset(cat, list, pid, createComponentAsset) }, cat)
+	}
+	return g.wait()
+}
+
+// create asset of a category of components, and assets of its reusable data types by a create function of the spec type
+func createComponentCategoryAsset(cat string, list interface{}, parent int, create func(cat, k string, v interface{}, cid int) error) error {
+	asset := Asset{
+		Name:                    cat,
+		Label:                   cat,
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	cid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+	om, ok := list.(map[string]interface{})
 	if !ok {
-		return errors.Errorf("components type %T is not a map", components)
+		return nil
 	}
-	for cat, list := range cm {
-		asset := Asset{
-			Name:                    cat,
-			Label:                   cat,
-			Parent:                  strconv.Itoa(pid),
-			AssetType:               AssetTypes["JSON Element"],
-			DataElementAutoAssigned: false,
-			IsDisabled:              false,
-		}
-		cid, err := createAsset(asset)
-		if err != nil {
-			continue
-		}
-		om, ok := list.(map[string]interface{})
-		if !ok {
-			continue
-		}
-		// create reusable data types
-		for k, v := range om {
-			tid := setRef(fmt.Sprintf("#/components/%s/%s", cat, k))
+	// create reusable data types
+	var g importGroup
+	for k, v := range om {
+		k, v := k, v
+		g.run(func() error { return create(cat, k, v, cid) }, k)
+	}
+	return g.wait()
+}
 
-			switch cat {
-			case "schemas":
-				createSchemaAsset(k, v, tid, cid, false)
-			case "messages":
-				createMessageAsset(k, v, tid, cid)
-			case "securitySchemes":
-				createSecuritySchemeAsset(k, v, tid, cid)
-			case "parameters":
-				createParameterAsset(k, v, tid, cid)
-			case "operationTraits":
-				createOperationTraitAsset(k, v, tid, cid)
-			case "messageTraits":
-				createMessageTraitAsset(k, v, tid, cid)
-			default:
-				fmt.Printf("component type %s not implemented", cat)
-			}
+// create asset of a reusable data type of a category of components
+func createComponentAsset(cat, k string, v interface{}, cid int) error {
+	tid, err := setRef(jsonPointer("components", cat, k))
+	if err != nil {
+		return err
+	}
 
-		}
+	switch cat {
+	case "schemas":
+		err = createSchemaAsset(k, v, tid, cid, false)
+	case "messages":
+		err = createMessageAsset(k, v, tid, cid)
+	case "securitySchemes":
+		err = createSecuritySchemeAsset(k, v, tid, cid)
+	case "parameters":
+		err = createParameterAsset(k, v, tid, cid)
+	case "operationTraits":
+		err = createOperationTraitAsset(k, v, tid, cid)
+	case "messageTraits":
+		err = createMessageTraitAsset(k, v, tid, cid)
+	case "correlationIds":
+		err = createCorrelationIDAsset(k, v, tid, cid)
+	case "serverVariables":
+		err = createServerVariableAsset(k, v, tid, cid)
+	case "servers":
+		err = createServerAsset(k, v, tid, cid)
+	case "channels":
+		if isAsyncAPI3() {
+			err = createChannel3Asset(k, v, tid, cid, jsonPointer("components", cat, k))
+		} else {
+			err = createChannelAsset(k, v, tid, cid)
+		}
+	case "operations":
+		err = createOperation3Asset(k, v, tid, cid)
+	case "replies":
+		err = createOperationReplyAsset(k, v, tid, cid)
+	case "replyAddresses":
+		// reply address has the same description and location as a correlation ID
+		err = createCorrelationIDAsset(k, v, tid, cid)
+	case "tags", "externalDocs":
+		err = createComponentValueAsset(k, v, tid, cid)
+	case "serverBindings", "channelBindings", "operationBindings", "messageBindings":
+		err = createComponentBindingsAsset(k, v, strings.TrimSuffix(cat, "Bindings"), tid, cid)
+	default:
+		fmt.Printf("component type %s not implemented", cat)
 	}
-	return nil
+	return err
 }
 
+// schema keywords of a list of subschemas
+var schemaCompositions = []string{"allOf", "anyOf", "oneOf"}
+
+// create schema asset, with child assets for its properties, items, and subschemas of allOf, anyOf, oneOf and not.
+// properties are created as JSON Property, so they are not confused with other subschemas of the same name.
 func createSchemaAsset(name string, data interface{}, tid int, parent int, isProperty bool) error {
-	comment := extractExtraProperties(data.(map[string]interface{}), []string{"$ref", "description", "properties", "x-examples", "examples"})
+	dm, ok := data.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("schema %s type %T is not a map", name, data)
+	}
+	exclude := append([]string{"$ref", "description", "items", "not"}, schemaCompositions...)
+	if pm, ok := getRef(data, "#/properties").(map[string]interface{}); !ok || len(pm) > 0 {
+		// keep empty properties in comment since it does not create any child asset
+		exclude = append(exclude, "properties")
+	}
+	comment := extractExtraProperties(dm, exclude)
 	asset := Asset{
 		Name:                    name,
 		Label:                   name,
@@ -304,7 +457,7 @@ func createSchemaAsset(name string, data interface{}, tid int, parent int, isPro
 		// not a component type, so set primitive data type
 		dtype := getString(data, "#/type")
 		if len(dtype) > 0 && dtype != "object" {
-			if t, ok := AssetDataTypes[dtype]; ok {
+			if t := dataTypeID(dtype); t > 0 {
 				dtid = t
 			}
 		}
@@ -317,20 +470,77 @@ func createSchemaAsset(name string, data interface{}, tid int, parent int, isPro
 		return err
 	}
 
+	var errs specErrors
 	if props := getRef(data, "#/properties"); props != nil {
 		if pm, ok := props.(map[string]interface{}); ok {
+			var g importGroup
 			for k, v := range pm {
-				ctid := 0
-				if ref := getString(v, "#/$ref"); len(ref) > 0 {
-					ctid = setRef(ref)
-				}
-				//TODO: array type is assumed as simple primitive types
-				createSchemaAsset(k, v, ctid, pid, true)
-				//				createPropertyAsset(k, v, pid)
+				k, v := k, v
+				g.run(func() error {
+					ctid, err := refDataType(v)
+					if err != nil {
+						return err
+					}
+					return createSchemaAsset(k, v, ctid, pid, true)
+				}, k)
 			}
+			errs.add(g.wait(), "properties")
 		}
 	}
-	return nil
+
+	if items, ok := dm["items"]; ok {
+		if _, ok := items.([]interface{}); ok {
+			// tuple validation of array items
+			errs.add(createSchemaListAsset("items", items, pid), "items")
+		} else {
+			errs.add(createSubschemaAsset("items", items, pid), "items")
+		}
+	}
+	for _, kw := range schemaCompositions {
+		if list, ok := dm[kw]; ok {
+			errs.add(createSchemaListAsset(kw, list, pid), kw)
+		}
+	}
+	if not, ok := dm["not"]; ok {
+		errs.add(createSubschemaAsset("not", not, pid), "not")
+	}
+	return errs.result()
+}
+
+// create a subschema that may refer to a component data type
+func createSubschemaAsset(name string, data interface{}, parent int) error {
+	tid, err := refDataType(data)
+	if err != nil {
+		return err
+	}
+	return createSchemaAsset(name, data, tid, parent, false)
+}
+
+// create an array asset for a list of subschemas, each is named by its index in the list
+func createSchemaListAsset(name string, list interface{}, parent int) error {
+	schemas, ok := list.([]interface{})
+	if !ok {
+		return errors.Errorf("%s type %T is not an array", name, list)
+	}
+	asset := Asset{
+		Name:                    name,
+		Label:                   name,
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		AssetDataType:           strconv.Itoa(dataTypeID("array")),
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+
+	var errs specErrors
+	for i, v := range schemas {
+		errs.add(createSubschemaAsset(strconv.Itoa(i), v, pid), strconv.Itoa(i))
+	}
+	return errs.result()
 }
 
 // return JSON of data excluding specified properties
@@ -352,7 +562,11 @@ func extractExtraProperties(data map[string]interface{}, exclude []string) strin
 	return string(props)
 }
 
-func createChannelsAsset(channels map[string]interface{}, parent int) error {
+func createChannelsAsset(channels interface{}, parent int) error {
+	cm, ok := channels.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("channels type %T is not a map", channels)
+	}
 	asset := Asset{
 		Name:                    "channels",
 		Label:                   "channels",
@@ -366,15 +580,21 @@ func createChannelsAsset(channels map[string]interface{}, parent int) error {
 		return err
 	}
 
-	for k, v := range channels {
-		if err := createChannelAsset(k, v, pid); err != nil {
-			return err
-		}
+	var g importGroup
+	for k, v := range cm {
+		k, v := k, v
+		g.run(func() error {
+			tid, err := refDataType(v)
+			if err != nil {
+				return err
+			}
+			return createChannelAsset(k, v, tid, pid)
+		}, k)
 	}
-	return nil
+	return g.wait()
 }
 
-func createChannelAsset(name string, channel interface{}, parent int) error {
+func createChannelAsset(name string, channel interface{}, tid int, parent int) error {
 	props, ok := channel.(map[string]interface{})
 	if !ok {
 		return errors.Errorf("No properties for channel %s", name)
@@ -383,15 +603,14 @@ func createChannelAsset(name string, channel interface{}, parent int) error {
 		Name:                    name,
 		Label:                   name,
 		Description:             getString(channel, "#/description"),
+		Comment:                 extractExtraProperties(props, []string{"$ref", "description", "parameters", "subscribe", "publish", "bindings"}),
 		Parent:                  strconv.Itoa(parent),
 		AssetType:               AssetTypes["JSON Element"],
 		DataElementAutoAssigned: false,
 		IsDisabled:              false,
 	}
-	if ref := getString(channel, "#/$ref"); len(ref) > 0 {
-		if tid := setRef(ref); tid > 0 {
-			asset.AssetDataType = strconv.Itoa(tid)
-		}
+	if tid > 0 {
+		asset.AssetDataType = strconv.Itoa(tid)
 	}
 
 	pid, err := createAsset(asset)
@@ -399,21 +618,28 @@ func createChannelAsset(name string, channel interface{}, parent int) error {
 		return err
 	}
 
+	var errs specErrors
 	if params, ok := props["parameters"]; ok {
-		createParametersAsset(params, pid)
+		errs.add(createParametersAsset(params, pid), "parameters")
 	}
 
 	for _, op := range []string{"subscribe", "publish"} {
 		if val, ok := props[op]; ok {
-			createOperationAsset(op, val, pid)
+			errs.add(createOperationAsset(op, val, pid), op)
 		}
 	}
 
-	//TODO: process channel binding object
-	return nil
+	if bindings, ok := props["bindings"]; ok {
+		errs.add(createBindingsAsset(bindings, "channel", pid), "bindings")
+	}
+	return errs.result()
 }
 
 func createParametersAsset(params interface{}, parent int) error {
+	ps, ok := params.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("parameters type %T is not a map", params)
+	}
 	asset := Asset{
 		Name:                    "parameters",
 		Label:                   "parameters",
@@ -426,23 +652,28 @@ func createParametersAsset(params interface{}, parent int) error {
 	if err != nil {
 		return err
 	}
-	if ps, ok := params.(map[string]interface{}); ok {
-		for k, v := range ps {
-			tid := 0
-			if ref := getString(v, "#/$ref"); len(ref) > 0 {
-				tid = setRef(ref)
-			}
-			createParameterAsset(k, v, tid, pid)
+
+	var errs specErrors
+	for k, v := range ps {
+		tid, err := refDataType(v)
+		if err == nil {
+			err = createParameterAsset(k, v, tid, pid)
 		}
+		errs.add(err, k)
 	}
-	return nil
+	return errs.result()
 }
 
 func createParameterAsset(name string, parameter interface{}, tid int, parent int) error {
+	pm, ok := parameter.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("parameter %s type %T is not a map", name, parameter)
+	}
 	asset := Asset{
 		Name:                    name,
 		Label:                   name,
 		Description:             getString(parameter, "#/description"),
+		Comment:                 extractExtraProperties(pm, []string{"$ref", "description", "location", "schema", "default", "enum"}),
 		Parent:                  strconv.Itoa(parent),
 		AssetType:               AssetTypes["JSON Element"],
 		DataElementAutoAssigned: false,
@@ -456,22 +687,36 @@ func createParameterAsset(name string, parameter interface{}, tid int, parent in
 		return err
 	}
 
+	var errs specErrors
 	if loc := getString(parameter, "#/location"); len(loc) > 0 {
-		createSimpleAsset("location", loc, pid, "string")
+		_, err := createSimpleAsset("location", loc, pid, "string")
+		errs.add(err, "location")
 	}
 
 	if schema := getRef(parameter, "#/schema"); schema != nil {
-		tid := 0
-		if ref := getString(schema, "#/$ref"); len(ref) > 0 {
-			tid = setRef(ref)
+		tid, err := refDataType(schema)
+		if err == nil {
+			err = createSchemaAsset("schema", schema, tid, pid, false)
 		}
-		createSchemaAsset("schema", schema, tid, pid, false)
+		errs.add(err, "schema")
 	}
-	return nil
+
+	// AsyncAPI 3.0 parameter replaces schema by default and enum values
+	if def, ok := pm["default"]; ok {
+		errs.add(createValueAsset("default", def, pid), "default")
+	}
+	if enum, ok := pm["enum"]; ok {
+		errs.add(createEnumAsset(enum, pid), "enum")
+	}
+	return errs.result()
 }
 
 func createOperationAsset(name string, operation interface{}, parent int) error {
-	comment := extractExtraProperties(operation.(map[string]interface{}), []string{"description", "tags", "externalDocs", "traits", "message", "bindings"})
+	op, ok := operation.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("operation %s type %T is not a map", name, operation)
+	}
+	comment := extractExtraProperties(op, []string{"operationId", "description", "security", "tags", "externalDocs", "traits", "message", "bindings"})
 	asset := Asset{
 		Name:                    name,
 		Label:                   name,
@@ -487,26 +732,43 @@ func createOperationAsset(name string, operation interface{}, parent int) error
 		return err
 	}
 
-	if tags := getRef(operation, "#/tags"); tags != nil {
-		createTagsAsset(tags, pid)
-	}
-
-	if externalDocs := getRef(operation, "#/externalDocs"); externalDocs != nil {
-		createExternalDocsAsset(externalDocs, pid)
-	}
-
+	var errs specErrors
+	errs.add(createOperationDetailAssets(operation, pid))
 	if traits := getRef(operation, "#/traits"); traits != nil {
-		createOperationTraitsAsset(traits, pid)
+		errs.add(createOperationTraitsAsset(traits, pid), "traits")
 	}
 
 	if msg := getRef(operation, "#/message"); msg != nil {
-		tid := 0
-		if ref := getString(msg, "#/$ref"); len(ref) > 0 {
-			tid = setRef(ref)
+		tid, err := refDataType(msg)
+		if err == nil {
+			err = createMessageAsset("message", msg, tid, pid)
 		}
-		createMessageAsset("message", msg, tid, pid)
+		errs.add(err, "message")
 	}
-	return nil
+
+	return errs.result()
+}
+
+// create child assets for operationId, security, tags, externalDocs and bindings of an operation or operation trait
+func createOperationDetailAssets(operation interface{}, parent int) error {
+	var errs specErrors
+	if operationID := getString(operation, "#/operationId"); len(operationID) > 0 {
+		_, err := createSimpleAsset("operationId", operationID, parent, "string")
+		errs.add(err, "operationId")
+	}
+	if security := getRef(operation, "#/security"); security != nil {
+		errs.add(createSecurityRequirementAsset(security, parent), "security")
+	}
+	if tags := getRef(operation, "#/tags"); tags != nil {
+		errs.add(createTagsAsset(tags, parent), "tags")
+	}
+	if externalDocs := getRef(operation, "#/externalDocs"); externalDocs != nil {
+		errs.add(createExternalDocsAsset(externalDocs, parent), "externalDocs")
+	}
+	if bindings := getRef(operation, "#/bindings"); bindings != nil {
+		errs.add(createBindingsAsset(bindings, "operation", parent), "bindings")
+	}
+	return errs.result()
 }
 
 func createOperationTraitsAsset(traits interface{}, parent int) error {
@@ -519,7 +781,7 @@ func createOperationTraitsAsset(traits interface{}, parent int) error {
 		Label:                   "traits",
 		Parent:                  strconv.Itoa(parent),
 		AssetType:               AssetTypes["JSON Element"],
-		AssetDataType:           strconv.Itoa(AssetDataTypes["array"]),
+		AssetDataType:           strconv.Itoa(dataTypeID("array")),
 		DataElementAutoAssigned: false,
 		IsDisabled:              false,
 	}
@@ -528,23 +790,30 @@ func createOperationTraitsAsset(traits interface{}, parent int) error {
 		return err
 	}
 
+	var errs specErrors
 	for i, trait := range ts {
 		name := fmt.Sprintf("trait-%d", i)
 		if nm := getString(trait, "#/name"); len(nm) > 0 {
 			name = nm
 		}
-		tid := 0
-		if ref := getString(trait, "#/$ref"); len(ref) > 0 {
-			tid = setRef(ref)
-			name = ref[strings.LastIndex(ref, "/")+1:]
+		tid, err := refDataType(trait)
+		if err == nil {
+			if ref := getString(trait, "#/$ref"); len(ref) > 0 {
+				name = ref[strings.LastIndex(ref, "/")+1:]
+			}
+			err = createOperationTraitAsset(name, trait, tid, pid)
 		}
-		createOperationTraitAsset(name, trait, tid, pid)
+		errs.add(err, strconv.Itoa(i))
 	}
-	return nil
+	return errs.result()
 }
 
 func createOperationTraitAsset(name string, trait interface{}, tid int, parent int) error {
-	comment := extractExtraProperties(trait.(map[string]interface{}), []string{"$ref", "externalDocs", "description", "tags", "bindings"})
+	tm, ok := trait.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("operation trait %s type %T is not a map", name, trait)
+	}
+	comment := extractExtraProperties(tm, []string{"$ref", "operationId", "security", "externalDocs", "description", "tags", "bindings"})
 	asset := Asset{
 		Name:                    name,
 		Label:                   name,
@@ -563,22 +832,15 @@ func createOperationTraitAsset(name string, trait interface{}, tid int, parent i
 		return err
 	}
 
-	if externalDocs := getRef(trait, "#/externalDocs"); externalDocs != nil {
-		createExternalDocsAsset(externalDocs, pid)
-	}
-	if tags := getRef(trait, "#/tags"); tags != nil {
-		createTagsAsset(tags, pid)
-	}
-	if bindings := getRef(trait, "#/bindings"); bindings != nil {
-		if bv, err := json.MarshalIndent(bindings, "", "    "); err == nil {
-			createSimpleAsset("bindings", string(bv), pid, "")
-		}
-	}
-	return nil
+	return createOperationDetailAssets(trait, pid)
 }
 
 func createMessageAsset(name string, message interface{}, tid int, parent int) error {
-	comment := extractExtraProperties(message.(map[string]interface{}), []string{"$ref", "headers", "correlationId", "externalDocs", "description", "tags", "payload", "bindings", "examples", "traits"})
+	mm, ok := message.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("message %s type %T is not a map", name, message)
+	}
+	comment := extractExtraProperties(mm, []string{"$ref", "messageId", "headers", "correlationId", "externalDocs", "description", "tags", "payload", "bindings", "examples", "traits"})
 	asset := Asset{
 		Name:                    name,
 		Label:                   name,
@@ -597,30 +859,157 @@ func createMessageAsset(name string, message interface{}, tid int, parent int) e
 		return err
 	}
 
+	var errs specErrors
 	if externalDocs := getRef(message, "#/externalDocs"); externalDocs != nil {
-		createExternalDocsAsset(externalDocs, mid)
+		errs.add(createExternalDocsAsset(externalDocs, mid), "externalDocs")
 	}
 	if tags := getRef(message, "#/tags"); tags != nil {
-		createTagsAsset(tags, mid)
+		errs.add(createTagsAsset(tags, mid), "tags")
 	}
 	if payload := getRef(message, "#/payload"); payload != nil {
-		tid := 0
-		if ref := getString(payload, "#/$ref"); len(ref) > 0 {
-			tid = setRef(ref)
+		tid, err := refDataType(payload)
+		if err == nil {
+			err = createSchemaAsset("payload", payload, tid, mid, false)
 		}
-		createSchemaAsset("payload", payload, tid, mid, false)
+		errs.add(err, "payload")
 	}
 
 	if traits := getRef(message, "#/traits"); traits != nil {
-		createMessageTraitsAsset(traits, mid)
+		errs.add(createMessageTraitsAsset(traits, mid), "traits")
+	}
+	errs.add(createMessageDetailAssets(message, mid))
+	return errs.result()
+}
+
+// create child assets for messageId, headers, correlationId, bindings and examples of a message or message trait
+func createMessageDetailAssets(message interface{}, parent int) error {
+	var errs specErrors
+	if messageID := getString(message, "#/messageId"); len(messageID) > 0 {
+		_, err := createSimpleAsset("messageId", messageID, parent, "string")
+		errs.add(err, "messageId")
+	}
+	if headers := getRef(message, "#/headers"); headers != nil {
+		tid, err := refDataType(headers)
+		if err == nil {
+			err = createSchemaAsset("headers", headers, tid, parent, false)
+		}
+		errs.add(err, "headers")
+	}
+	if correlationID := getRef(message, "#/correlationId"); correlationID != nil {
+		tid, err := refDataType(correlationID)
+		if err == nil {
+			err = createCorrelationIDAsset("correlationId", correlationID, tid, parent)
+		}
+		errs.add(err, "correlationId")
+	}
+	if bindings := getRef(message, "#/bindings"); bindings != nil {
+		errs.add(createBindingsAsset(bindings, "message", parent), "bindings")
+	}
+	if examples := getRef(message, "#/examples"); examples != nil {
+		errs.add(createMessageExamplesAsset(examples, parent), "examples")
+	}
+	return errs.result()
+}
+
+// create examples of a message or message trait, with a child asset per example in the order of the array
+func createMessageExamplesAsset(examples interface{}, parent int) error {
+	es, ok := examples.([]interface{})
+	if !ok {
+		return errors.Errorf("message examples %T is not an array", examples)
+	}
+	asset := Asset{
+		Name:                    "examples",
+		Label:                   "examples",
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		AssetDataType:           strconv.Itoa(dataTypeID("array")),
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+
+	var errs specErrors
+	for i, example := range es {
+		errs.add(createMessageExampleAsset(fmt.Sprintf("example-%d", i), example, pid), strconv.Itoa(i))
+	}
+	return errs.result()
+}
+
+// message example contains headers and payload, and optional name and summary since AsyncAPI 2.1.
+// The name is kept in comment, so an example without name is exported without it.
+// An example that is not an object, which AsyncAPI 2.0 allows, is stored as JSON.
+func createMessageExampleAsset(name string, example interface{}, parent int) error {
+	em, ok := example.(map[string]interface{})
+	if !ok {
+		return createJSONAsset(name, example, parent)
+	}
+	if nm := getString(example, "#/name"); len(nm) > 0 {
+		name = nm
+	}
+	asset := Asset{
+		Name:                    name,
+		Label:                   name,
+		Description:             getString(example, "#/summary"),
+		Comment:                 extractExtraProperties(em, []string{"summary", "headers", "payload"}),
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	eid, err := createAsset(asset)
+	if err != nil {
+		return err
 	}
 
-	//TODO: ignored headers, correlationId, bindings, examples
+	var errs specErrors
+	for _, p := range []string{"headers", "payload"} {
+		if v, ok := em[p]; ok {
+			errs.add(createJSONAsset(p, v, eid), p)
+		}
+	}
+	return errs.result()
+}
+
+// correlation ID object contains description and location of the correlation ID in a message
+func createCorrelationIDAsset(name string, data interface{}, tid int, parent int) error {
+	dm, ok := data.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("correlation ID %s type %T is not a map", name, data)
+	}
+	asset := Asset{
+		Name:                    name,
+		Label:                   name,
+		Description:             getString(data, "#/description"),
+		Comment:                 extractExtraProperties(dm, []string{"$ref", "description", "location"}),
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	if tid > 0 {
+		asset.AssetDataType = strconv.Itoa(tid)
+	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+
+	if loc := getString(data, "#/location"); len(loc) > 0 {
+		_, err := createSimpleAsset("location", loc, pid, "string")
+		return atNode(err, "location")
+	}
 	return nil
 }
 
 func createSecuritySchemeAsset(name string, data interface{}, tid int, parent int) error {
-	comment := extractExtraProperties(data.(map[string]interface{}), []string{"$ref", "description", "flows"})
+	dm, ok := data.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("security scheme %s type %T is not a map", name, data)
+	}
+	comment := extractExtraProperties(dm, []string{"$ref", "description", "flows"})
 	asset := Asset{
 		Name:                    name,
 		Label:                   name,
@@ -640,12 +1029,16 @@ func createSecuritySchemeAsset(name string, data interface{}, tid int, parent in
 	}
 
 	if flows := getRef(data, "#/flows"); flows != nil {
-		createOAuthFlowsAsset(flows, pid)
+		return atNode(createOAuthFlowsAsset(flows, pid), "flows")
 	}
 	return nil
 }
 
 func createOAuthFlowsAsset(flows interface{}, parent int) error {
+	fm, ok := flows.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("flows %T is not a map", flows)
+	}
 	asset := Asset{
 		Name:                    "flows",
 		Label:                   "flows",
@@ -659,17 +1052,17 @@ func createOAuthFlowsAsset(flows interface{}, parent int) error {
 		return err
 	}
 
-	fm, ok := flows.(map[string]interface{})
-	if !ok {
-		return errors.Errorf("flows %T is not a map", flows)
-	}
-
+	var errs specErrors
 	for k, v := range fm {
-		comment := extractExtraProperties(v.(map[string]interface{}), []string{"scopes"})
+		flow, ok := v.(map[string]interface{})
+		if !ok {
+			errs.add(errors.Errorf("flow type %T is not a map", v), k)
+			continue
+		}
 		asset := Asset{
 			Name:                    k,
 			Label:                   k,
-			Comment:                 comment,
+			Comment:                 extractExtraProperties(flow, []string{"scopes"}),
 			Parent:                  strconv.Itoa(pid),
 			AssetType:               AssetTypes["JSON Element"],
 			DataElementAutoAssigned: false,
@@ -677,16 +1070,21 @@ func createOAuthFlowsAsset(flows interface{}, parent int) error {
 		}
 		fid, err := createAsset(asset)
 		if err != nil {
+			errs.add(err, k)
 			continue
 		}
 		if scopes := getRef(v, "#/scopes"); scopes != nil {
-			createOAuthFlowScopesAsset(scopes, fid)
+			errs.add(createOAuthFlowScopesAsset(scopes, fid), k, "scopes")
 		}
 	}
-	return nil
+	return errs.result()
 }
 
 func createOAuthFlowScopesAsset(scopes interface{}, parent int) error {
+	sm, ok := scopes.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("flow scopes %T is not a map", scopes)
+	}
 	asset := Asset{
 		Name:                    "scopes",
 		Label:                   "scopes",
@@ -700,42 +1098,225 @@ func createOAuthFlowScopesAsset(scopes interface{}, parent int) error {
 		return err
 	}
 
-	sm, ok := scopes.(map[string]interface{})
+	var errs specErrors
+	for k, v := range sm {
+		_, err := createSimpleAsset(k, fmt.Sprintf("%v", v), pid, "string")
+		errs.add(err, k)
+	}
+	return errs.result()
+}
+
+// set asset data type for a ref name, create the type if necessary, and return the type ID
+func setRef(ref string) (int, error) {
+	name := scopedTypeName(ref)
+	if tid := dataTypeID(name); tid > 0 {
+		return tid, nil
+	}
+	unlock := lockDataType(name)
+	defer unlock()
+	// check again, since the data type may be created by another import while waiting for the lock
+	if tid := dataTypeID(name); tid > 0 {
+		return tid, nil
+	}
+	tid, err := findOrCreateAssetDataType(name, true)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Failed to set data type %s", name)
+	}
+	cacheDataTypeID(name, tid)
+	return tid, nil
+}
+
+// returns data type ID of the $ref of a node, or 0 if the node is not a ref
+func refDataType(node interface{}) (int, error) {
+	if ref := getString(node, "#/$ref"); len(ref) > 0 {
+		return setRef(ref)
+	}
+	return 0, nil
+}
+
+func createMessageTraitsAsset(traits interface{}, parent int) error {
+	ts, ok := traits.([]interface{})
 	if !ok {
-		return errors.Errorf("flow scopes %T is not a map", scopes)
+		return errors.Errorf("message traits %T is not an array", traits)
+	}
+	asset := Asset{
+		Name:                    "traits",
+		Label:                   "traits",
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		AssetDataType:           strconv.Itoa(dataTypeID("array")),
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+
+	var errs specErrors
+	for i, trait := range ts {
+		name := fmt.Sprintf("trait-%d", i)
+		if nm := getString(trait, "#/name"); len(nm) > 0 {
+			name = nm
+		}
+		tid, err := refDataType(trait)
+		if err == nil {
+			if ref := getString(trait, "#/$ref"); len(ref) > 0 {
+				name = ref[strings.LastIndex(ref, "/")+1:]
+			}
+			err = createMessageTraitAsset(name, trait, tid, pid)
+		}
+		errs.add(err, strconv.Itoa(i))
+	}
+	return errs.result()
+}
+
+func createMessageTraitAsset(name string, trait interface{}, tid int, parent int) error {
+	tm, ok := trait.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("message trait %s type %T is not a map", name, trait)
+	}
+	comment := extractExtraProperties(tm, []string{"$ref", "messageId", "headers", "correlationId", "externalDocs", "description", "tags", "bindings", "examples"})
+	asset := Asset{
+		Name:                    name,
+		Label:                   name,
+		Description:             getString(trait, "#/description"),
+		Comment:                 comment,
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	if tid > 0 {
+		asset.AssetDataType = strconv.Itoa(tid)
+	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+
+	var errs specErrors
+	if externalDocs := getRef(trait, "#/externalDocs"); externalDocs != nil {
+		errs.add(createExternalDocsAsset(externalDocs, pid), "externalDocs")
+	}
+	if tags := getRef(trait, "#/tags"); tags != nil {
+		errs.add(createTagsAsset(tags, pid), "tags")
+	}
+	errs.add(createMessageDetailAssets(trait, pid))
+	return errs.result()
+}
+
+func createServersAsset(servers interface{}, parent int) error {
+	sm, ok := servers.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("servers type %T is not a map", servers)
+	}
+	asset := Asset{
+		Name:                    "servers",
+		Label:                   "servers",
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+
+	var errs specErrors
+	for k, v := range sm {
+		tid, err := refDataType(v)
+		if err == nil {
+			err = createServerAsset(k, v, tid, pid)
+		}
+		errs.add(err, k)
+	}
+	return errs.result()
+}
+
+// server properties that are created as simple string assets, AsyncAPI 3.0 replaces url by host and pathname
+var serverSimpleProperties = []string{"url", "host", "pathname", "protocol", "protocolVersion"}
+
+func createServerAsset(name string, data interface{}, tid int, parent int) error {
+	server, ok := data.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("server %s type %T is not a map", name, data)
+	}
+	exclude := append([]string{"$ref", "description", "variables", "security", "tags", "bindings"}, serverSimpleProperties...)
+	comment := extractExtraProperties(server, exclude)
+	asset := Asset{
+		Name:                    name,
+		Label:                   name,
+		Description:             getString(server, "#/description"),
+		Comment:                 comment,
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	if tid > 0 {
+		asset.AssetDataType = strconv.Itoa(tid)
+	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+
+	var errs specErrors
+	for _, p := range serverSimpleProperties {
+		if v, ok := server[p]; ok {
+			_, err := createSimpleAsset(p, fmt.Sprintf("%v", v), pid, "string")
+			errs.add(err, p)
+		}
+	}
+
+	variables := getRef(server, "#/variables")
+	url := getString(server, "#/url") + getString(server, "#/host") + getString(server, "#/pathname")
+	for _, v := range urlTemplateVariables(url) {
+		if getRef(variables, "#/"+v) == nil {
+			fmt.Printf("server %s url variable %s is not defined\n", name, v)
+		}
+	}
+	if variables != nil {
+		errs.add(createServerVariablesAsset(variables, pid), "variables")
+	}
+
+	if security, ok := server["security"]; ok {
+		errs.add(createSecurityRequirementAsset(security, pid), "security")
 	}
 
-	for k, v := range sm {
-		createSimpleAsset(k, v.(string), pid, "string")
+	if tags, ok := server["tags"]; ok {
+		errs.add(createTagsAsset(tags, pid), "tags")
 	}
-	return nil
+
+	if bindings, ok := server["bindings"]; ok {
+		errs.add(createBindingsAsset(bindings, "server", pid), "bindings")
+	}
+	return errs.result()
 }
 
-// set asset data type for a ref name, create the type if necessary.
-// return type id if succesful, 0 otherwise
-func setRef(ref string) int {
-	tid, ok := AssetDataTypes[ref]
-	var err error
-	if !ok {
-		if tid, err = findOrCreateAssetDataType(ref, true); err != nil {
-			return 0
-		}
-		AssetDataTypes[ref] = tid
+var urlVariablePattern = regexp.MustCompile(`{([^{}]+)}`)
+
+// returns names of variables in a url template, e.g., port of localhost:{port}
+func urlTemplateVariables(url string) []string {
+	var result []string
+	for _, m := range urlVariablePattern.FindAllStringSubmatch(url, -1) {
+		result = append(result, m[1])
 	}
-	return tid
+	return result
 }
 
-func createMessageTraitsAsset(traits interface{}, parent int) error {
-	ts, ok := traits.([]interface{})
+func createServerVariablesAsset(variables interface{}, parent int) error {
+	vm, ok := variables.(map[string]interface{})
 	if !ok {
-		return errors.Errorf("message traits %T is not an array", traits)
+		return errors.Errorf("server variables type %T is not a map", variables)
 	}
 	asset := Asset{
-		Name:                    "traits",
-		Label:                   "traits",
+		Name:                    "variables",
+		Label:                   "variables",
 		Parent:                  strconv.Itoa(parent),
 		AssetType:               AssetTypes["JSON Element"],
-		AssetDataType:           strconv.Itoa(AssetDataTypes["array"]),
 		DataElementAutoAssigned: false,
 		IsDisabled:              false,
 	}
@@ -744,28 +1325,28 @@ func createMessageTraitsAsset(traits interface{}, parent int) error {
 		return err
 	}
 
-	for i, trait := range ts {
-		name := fmt.Sprintf("trait-%d", i)
-		if nm := getString(trait, "#/name"); len(nm) > 0 {
-			name = nm
+	var errs specErrors
+	for k, v := range vm {
+		tid, err := refDataType(v)
+		if err == nil {
+			err = createServerVariableAsset(k, v, tid, pid)
 		}
-		tid := 0
-		if ref := getString(trait, "#/$ref"); len(ref) > 0 {
-			tid = setRef(ref)
-			name = ref[strings.LastIndex(ref, "/")+1:]
-		}
-		createMessageTraitAsset(name, trait, tid, pid)
+		errs.add(err, k)
 	}
-	return nil
+	return errs.result()
 }
 
-func createMessageTraitAsset(name string, trait interface{}, tid int, parent int) error {
-	comment := extractExtraProperties(trait.(map[string]interface{}), []string{"$ref", "headers", "correlationId", "externalDocs", "description", "tags", "bindings", "examples"})
+// server variable is created with child assets of its default value and enum values
+func createServerVariableAsset(name string, variable interface{}, tid int, parent int) error {
+	vm, ok := variable.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("server variable %s type %T is not a map", name, variable)
+	}
 	asset := Asset{
 		Name:                    name,
 		Label:                   name,
-		Description:             getString(trait, "#/description"),
-		Comment:                 comment,
+		Description:             getString(variable, "#/description"),
+		Comment:                 extractExtraProperties(vm, []string{"$ref", "description", "default", "enum"}),
 		Parent:                  strconv.Itoa(parent),
 		AssetType:               AssetTypes["JSON Element"],
 		DataElementAutoAssigned: false,
@@ -779,26 +1360,27 @@ func createMessageTraitAsset(name string, trait interface{}, tid int, parent int
 		return err
 	}
 
-	if externalDocs := getRef(trait, "#/externalDocs"); externalDocs != nil {
-		createExternalDocsAsset(externalDocs, pid)
+	var errs specErrors
+	if def, ok := vm["default"]; ok {
+		errs.add(createValueAsset("default", def, pid), "default")
 	}
-	if tags := getRef(trait, "#/tags"); tags != nil {
-		createTagsAsset(tags, pid)
-	}
-	if headers := getRef(trait, "#/headers"); headers != nil {
-		createSchemaAsset("headers", headers, 0, pid, false)
+	if enum, ok := vm["enum"]; ok {
+		errs.add(createEnumAsset(enum, pid), "enum")
 	}
-
-	//TODO: ignored correlationId, bindings, examples
-	return nil
+	return errs.result()
 }
 
-func createServersAsset(servers interface{}, parent int) error {
+func createEnumAsset(enum interface{}, parent int) error {
+	values, ok := enum.([]interface{})
+	if !ok {
+		return errors.Errorf("enum %T is not an array", enum)
+	}
 	asset := Asset{
-		Name:                    "servers",
-		Label:                   "servers",
+		Name:                    "enum",
+		Label:                   "enum",
 		Parent:                  strconv.Itoa(parent),
 		AssetType:               AssetTypes["JSON Element"],
+		AssetDataType:           strconv.Itoa(dataTypeID("array")),
 		DataElementAutoAssigned: false,
 		IsDisabled:              false,
 	}
@@ -807,50 +1389,47 @@ func createServersAsset(servers interface{}, parent int) error {
 		return err
 	}
 
-	for k, v := range servers.(map[string]interface{}) {
-		if svr, ok := v.(map[string]interface{}); ok {
-			err := createServerAsset(k, svr, pid)
+	var errs specErrors
+	for i, v := range values {
+		name := fmt.Sprintf("%v", v)
+		asset := Asset{
+			Name:                    name,
+			Label:                   name,
+			Parent:                  strconv.Itoa(pid),
+			AssetType:               AssetTypes["JSON Property"],
+			DataElementAutoAssigned: false,
+			IsDisabled:              false,
+		}
+		if _, ok := v.(string); ok {
+			asset.AssetDataType = strconv.Itoa(dataTypeID("string"))
+		} else {
+			// keep JSON of a number or boolean value, so it is not exported as string
+			data, err := json.Marshal(v)
 			if err != nil {
-				return err
+				errs.add(errors.Wrapf(err, "Failed to serialize enum value %s", name), strconv.Itoa(i))
+				continue
 			}
+			asset.Comment = string(data)
 		}
+		_, err := createAsset(asset)
+		errs.add(err, strconv.Itoa(i))
 	}
-	return nil
-}
-
-func createServerAsset(name string, server map[string]interface{}, parent int) error {
-	comment := extractExtraProperties(server, []string{"description", "security", "bindings"})
-	asset := Asset{
-		Name:                    name,
-		Label:                   name,
-		Description:             server["description"].(string),
-		Comment:                 comment,
-		Parent:                  strconv.Itoa(parent),
-		AssetType:               AssetTypes["JSON Element"],
-		DataElementAutoAssigned: false,
-		IsDisabled:              false,
-	}
-	pid, err := createAsset(asset)
-	if err != nil {
-		return err
-	}
-
-	if security, ok := server["security"]; ok {
-		createSecurityRequirementAsset(security, pid)
-	}
-
-	// TODO: handle server binding
-	return nil
+	return errs.result()
 }
 
-// server security requirement lists security schemes and scopes defined in #/components/securitySchemes
+// security requirement lists security schemes and scopes defined in #/components/securitySchemes.
+// AsyncAPI 3.0 lists security scheme objects or refs instead, which are created as JSON Element.
 func createSecurityRequirementAsset(security interface{}, parent int) error {
+	schemes, ok := security.([]interface{})
+	if !ok {
+		return errors.Errorf("security requirements %T is not an array", security)
+	}
 	asset := Asset{
 		Name:                    "security",
 		Label:                   "security",
 		Parent:                  strconv.Itoa(parent),
 		AssetType:               AssetTypes["JSON Element"],
-		AssetDataType:           strconv.Itoa(AssetDataTypes["array"]),
+		AssetDataType:           strconv.Itoa(dataTypeID("array")),
 		DataElementAutoAssigned: false,
 		IsDisabled:              false,
 	}
@@ -859,12 +1438,17 @@ func createSecurityRequirementAsset(security interface{}, parent int) error {
 		return err
 	}
 
-	if schemes, ok := security.([]interface{}); ok {
-		for _, s := range schemes {
-			createSecurityRequirementScheme(s, pid)
+	var errs specErrors
+	for i, s := range schemes {
+		if ref := getString(s, "#/$ref"); len(ref) > 0 {
+			errs.add(createRefAsset(ref[strings.LastIndex(ref, "/")+1:], s, pid), strconv.Itoa(i))
+		} else if len(getString(s, "#/type")) > 0 {
+			errs.add(createSecuritySchemeAsset(fmt.Sprintf("scheme-%d", i), s, 0, pid), strconv.Itoa(i))
+		} else {
+			errs.add(createSecurityRequirementScheme(s, pid), strconv.Itoa(i))
 		}
 	}
-	return nil
+	return errs.result()
 }
 
 func createSecurityRequirementScheme(scheme interface{}, parent int) error {
@@ -872,23 +1456,24 @@ func createSecurityRequirementScheme(scheme interface{}, parent int) error {
 	if !ok {
 		return errors.Errorf("security requirement scheme %T is not a map", scheme)
 	}
+	var errs specErrors
 	for k, v := range s {
 		asset := Asset{
 			Name:                    k,
 			Label:                   k,
 			Parent:                  strconv.Itoa(parent),
 			AssetType:               AssetTypes["JSON Property"],
-			AssetDataType:           strconv.Itoa(AssetDataTypes["array"]),
+			AssetDataType:           strconv.Itoa(dataTypeID("array")),
 			DataElementAutoAssigned: false,
 			IsDisabled:              false,
 		}
 		pid, err := createAsset(asset)
-		if err != nil {
-			return err
+		if err == nil {
+			err = createSecurityRequirementSchemeScopes(v, pid)
 		}
-		createSecurityRequirementSchemeScopes(v, pid)
+		errs.add(err, k)
 	}
-	return nil
+	return errs.result()
 }
 
 func createSecurityRequirementSchemeScopes(scopes interface{}, parent int) error {
@@ -896,44 +1481,75 @@ func createSecurityRequirementSchemeScopes(scopes interface{}, parent int) error
 	if !ok {
 		return errors.Errorf("security requirement scheme scope %T is not an array", scopes)
 	}
-	for _, scope := range ss {
+	var errs specErrors
+	for i, scope := range ss {
 		name := fmt.Sprintf("%s", scope)
 		asset := Asset{
 			Name:                    name,
 			Label:                   name,
 			Parent:                  strconv.Itoa(parent),
 			AssetType:               AssetTypes["JSON Property"],
-			AssetDataType:           strconv.Itoa(AssetDataTypes["string"]),
+			AssetDataType:           strconv.Itoa(dataTypeID("string")),
 			DataElementAutoAssigned: false,
 			IsDisabled:              false,
 		}
-		createAsset(asset)
+		_, err := createAsset(asset)
+		errs.add(err, strconv.Itoa(i))
 	}
-	return nil
+	return errs.result()
 }
 
-// NOT USED: replace component ref with actual definitions.
+// expandComponents replaces component $refs in a spec by copies of the definitions they refer to.
+// A recursive $ref to a component being expanded is kept, so components are kept in the spec for these $refs.
 func expandComponents(spec map[string]interface{}) error {
+	// components being expanded
 	refMap := make(map[string]interface{})
-	if err := dereference(spec, spec["components"], refMap); err != nil {
-		return err
-	}
-
-	if err := dereference(spec, spec["channels"], nil); err != nil {
-		return err
+	// expand all elements from the original components before they are replaced
+	expanded := make(map[string]interface{}, len(spec))
+	for k, v := range spec {
+		components, ok := v.(map[string]interface{})
+		if k != "components" || !ok {
+			elem, err := dereference(spec, v, refMap)
+			if err != nil {
+				return err
+			}
+			expanded[k] = elem
+			continue
+		}
+		result := make(map[string]interface{}, len(components))
+		for kind, defs := range components {
+			dm, ok := defs.(map[string]interface{})
+			if !ok {
+				result[kind] = defs
+				continue
+			}
+			rm := make(map[string]interface{}, len(dm))
+			for name, def := range dm {
+				// a component is being expanded while its own definition is expanded
+				path := jsonPointer("components", kind, name)
+				refMap[path] = true
+				elem, err := dereference(spec, def, refMap)
+				delete(refMap, path)
+				if err != nil {
+					return err
+				}
+				rm[name] = elem
+			}
+			result[kind] = rm
+		}
+		expanded[k] = result
 	}
-	if err := dereference(spec, spec["servers"], nil); err != nil {
-		return err
+	for k, v := range expanded {
+		spec[k] = v
 	}
-
 	return nil
 }
 
-// replace $Ref with component definition in an element and its descendants.
-// must initialize refMap if you want to perform deep deref on components
-func dereference(root map[string]interface{}, elem interface{}, refMap map[string]interface{}) error {
-	if elem == nil {
-		return nil
+// return a copy of an element and its descendants with $refs replaced by component definitions.
+// refMap contains component refs being expanded, which are kept to stop circular references.
+func dereference(root map[string]interface{}, elem interface{}, refMap map[string]interface{}) (interface{}, error) {
+	if path := refPath(elem); path != "" {
+		return dereferencePath(root, elem, path, refMap)
 	}
 
 	switch v := elem.(type) {
@@ -942,80 +1558,71 @@ func dereference(root map[string]interface{}, elem interface{}, refMap map[strin
 	case map[string]interface{}:
 		return dereferenceMap(root, v, refMap)
 	default:
-		return nil
+		return elem, nil
 	}
 }
 
-func dereferenceMap(root map[string]interface{}, elem map[string]interface{}, refMap map[string]interface{}) error {
+func dereferenceMap(root map[string]interface{}, elem map[string]interface{}, refMap map[string]interface{}) (interface{}, error) {
+	result := make(map[string]interface{}, len(elem))
 	for k, v := range elem {
-		if path := refPath(v); path != "" {
-			ref, err := dereferencePath(root, path, refMap)
-			if err != nil {
-				return err
-			}
-			elem[k] = ref
-		} else {
-			if err := dereference(root, v, refMap); err != nil {
-				return err
-			}
+		ref, err := dereference(root, v, refMap)
+		if err != nil {
+			return nil, err
 		}
+		result[k] = ref
 	}
-	return nil
+	return result, nil
 }
 
 // return the component ref path if elem is a $ref, or empty string otherwise
 func refPath(elem interface{}) string {
 	if elemap, ok := elem.(map[string]interface{}); ok {
-		if path, ok := elemap["$ref"]; ok {
-			return path.(string)
+		if path, ok := elemap["$ref"].(string); ok {
+			return path
 		}
 	}
 	return ""
 }
 
-func dereferenceArray(root map[string]interface{}, elem []interface{}, refMap map[string]interface{}) error {
+func dereferenceArray(root map[string]interface{}, elem []interface{}, refMap map[string]interface{}) (interface{}, error) {
+	result := make([]interface{}, len(elem))
 	for i, v := range elem {
-		if path := refPath(v); path != "" {
-			ref, err := dereferencePath(root, path, refMap)
-			if err != nil {
-				return err
-			}
-			elem[i] = ref
-		} else {
-			if err := dereference(root, v, refMap); err != nil {
-				return err
-			}
+		ref, err := dereference(root, v, refMap)
+		if err != nil {
+			return nil, err
 		}
+		result[i] = ref
 	}
-	return nil
+	return result, nil
 }
 
-func dereferencePath(root map[string]interface{}, path string, refMap map[string]interface{}) (interface{}, error) {
-	if refMap == nil {
-		// deref top component object only
-		return getRef(root, path), nil
+// return expanded definition of a $ref, or the $ref itself if it is not a component ref,
+// or it refers to a component being expanded
+func dereferencePath(root map[string]interface{}, elem interface{}, path string, refMap map[string]interface{}) (interface{}, error) {
+	if !strings.HasPrefix(path, "#/components/") {
+		return elem, nil
 	}
-
-	ref, ok := refMap[path]
-	if ok {
+	if _, ok := refMap[path]; ok {
 		// avoid circular reference in components
-		return ref, nil
+		return elem, nil
 	}
 
-	ref = getRef(root, path)
-	refMap[path] = ref
-
-	// deep deref for nested component refs
-	if err := dereference(root, ref, refMap); err != nil {
-		return nil, err
+	ref := getRef(root, path)
+	if ref == nil {
+		return nil, errors.Errorf("$ref %s is not found", path)
 	}
-	return ref, nil
+	// deep deref for nested component refs
+	refMap[path] = true
+	defer delete(refMap, path)
+	return dereference(root, ref, refMap)
 }
 
 func getRef(node interface{}, ref string) interface{} {
 	path := strings.Split(ref, "/")[1:]
 	c := node
 	for _, k := range path {
+		// unescape JSON pointer token, e.g., light~1measured -> light/measured
+		k = strings.Replace(strings.Replace(k, "~1", "/", -1), "~0", "~", -1)
 		v, ok := c.(map[string]interface{})
 		if !ok {
 			return nil
@@ -1039,7 +1646,7 @@ func getString(node interface{}, ref string) string {
 
 func exportAsyncAPISpec(name string) (interface{}, error) {
 	spec := make(map[string]interface{})
-	asset, err := getAssetByName(name)
+	asset, err := getRootAsset(name)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed to find root asset %s", name)
 	}
@@ -1049,9 +1656,16 @@ func exportAsyncAPISpec(name string) (interface{}, error) {
 	}
 
 	if children, err := getChildrenAsset(asset.ID); err == nil {
+		// export to the spec version recorded at import
+		asyncAPIVersion = ""
+		for _, child := range children {
+			if child.Label == "asyncapi" {
+				asyncAPIVersion = child.Comment
+			}
+		}
 		for _, child := range children {
 			switch child.Label {
-			case "asyncapi", "id":
+			case "asyncapi", "id", "defaultContentType":
 				extractSimpleAsset(&child, spec)
 			case "info":
 				extractInfoAsset(&child, spec)
@@ -1060,7 +1674,13 @@ func exportAsyncAPISpec(name string) (interface{}, error) {
 			case "servers":
 				extractServersAsset(&child, spec)
 			case "channels":
-				extractChannelsAsset(&child, spec)
+				if isAsyncAPI3() {
+					extractChannels3Asset(&child, spec)
+				} else {
+					extractChannelsAsset(&child, spec)
+				}
+			case "operations":
+				extractOperationsAsset(&child, spec)
 			case "tags":
 				extractTagsAsset(&child, spec)
 			case "externalDocs":
@@ -1109,6 +1729,10 @@ func extractInfoAsset(child *Asset, parent map[string]interface{}) error {
 			switch c.Label {
 			case "version", "contact":
 				extractSimpleAsset(&c, info)
+			case "tags":
+				extractTagsAsset(&c, info)
+			case "externalDocs":
+				extractExternalDocsAsset(&c, info)
 			default:
 				fmt.Println("Unknown child element", c.Label)
 			}
@@ -1134,9 +1758,12 @@ func extractTagsAsset(child *Asset, parent map[string]interface{}) error {
 	if children, err := getChildrenAsset(child.ID); err == nil {
 		tags := make([]interface{}, 0, len(children))
 		for _, c := range children {
-			tag := map[string]string{
-				"name": c.Label,
+			tag := make(map[string]interface{})
+			if ok := setComponentRef(&c, tag); ok {
+				tags = append(tags, tag)
+				continue
 			}
+			tag["name"] = c.Label
 			if len(c.Description) > 0 {
 				tag["description"] = c.Description
 			}
@@ -1153,27 +1780,42 @@ func extractServersAsset(child *Asset, parent map[string]interface{}) error {
 
 	if children, err := getChildrenAsset(child.ID); err == nil {
 		for _, c := range children {
-			extractServerAsset(&c, servers)
+			extractServerAsset(&c, servers, false)
 		}
 	}
 	return nil
 }
 
-func extractServerAsset(child *Asset, parent map[string]interface{}) error {
+func extractServerAsset(child *Asset, parent map[string]interface{}, isComponent bool) error {
 	server := make(map[string]interface{})
+	parent[child.Label] = server
+
+	if !isComponent {
+		if ok := setComponentRef(child, server); ok {
+			return nil
+		}
+	}
 	if len(child.Description) > 0 {
 		server["description"] = child.Description
 	}
-	parent[child.Label] = server
 	if len(child.Comment) > 0 {
 		extractComment(child.Comment, server)
 	}
 
 	if children, err := getChildrenAsset(child.ID); err == nil {
 		for _, c := range children {
-			if c.Label == "security" {
+			switch c.Label {
+			case "url", "host", "pathname", "protocol", "protocolVersion":
+				extractSimpleAsset(&c, server)
+			case "variables":
+				extractServerVariablesAsset(&c, server)
+			case "security":
 				extractSecurityRequirementAsset(&c, server)
-			} else {
+			case "tags":
+				extractTagsAsset(&c, server)
+			case "bindings":
+				extractBindingsAsset(&c, server)
+			default:
 				fmt.Printf("server child type %s is not implemented\n", c.Label)
 			}
 		}
@@ -1181,12 +1823,82 @@ func extractServerAsset(child *Asset, parent map[string]interface{}) error {
 	return nil
 }
 
+func extractServerVariablesAsset(child *Asset, parent map[string]interface{}) error {
+	variables := make(map[string]interface{})
+	parent["variables"] = variables
+
+	children, err := getChildrenAsset(child.ID)
+	if err != nil {
+		return errors.Wrap(err, "Failed to fetch server variables")
+	}
+	for _, c := range children {
+		extractServerVariableAsset(&c, variables, false)
+	}
+	return nil
+}
+
+func extractServerVariableAsset(child *Asset, parent map[string]interface{}, isComponent bool) error {
+	variable := make(map[string]interface{})
+	parent[child.Label] = variable
+
+	if !isComponent {
+		if ok := setComponentRef(child, variable); ok {
+			return nil
+		}
+	}
+	if len(child.Description) > 0 {
+		variable["description"] = child.Description
+	}
+	if len(child.Comment) > 0 {
+		extractComment(child.Comment, variable)
+	}
+	if children, err := getChildrenAsset(child.ID); err == nil {
+		for _, c := range children {
+			switch c.Label {
+			case "default":
+				extractSimpleAsset(&c, variable)
+			case "enum":
+				extractEnumAsset(&c, variable)
+			default:
+				fmt.Printf("server variable child type %s is not implemented\n", c.Label)
+			}
+		}
+	}
+	return nil
+}
+
+func extractEnumAsset(child *Asset, parent map[string]interface{}) error {
+	if children, err := getChildrenAsset(child.ID); err == nil {
+		enum := make([]interface{}, 0, len(children))
+		for _, c := range children {
+			var value interface{} = c.Label
+			if dataTypeName(&c) != "string" && len(c.Comment) > 0 {
+				if err := json.Unmarshal([]byte(c.Comment), &value); err != nil {
+					fmt.Printf("Failed to deserialize enum value %s: %v\n", c.Label, err)
+					value = c.Label
+				}
+			}
+			enum = append(enum, value)
+		}
+		parent["enum"] = enum
+	}
+	return nil
+}
+
 func extractSecurityRequirementAsset(child *Asset, parent map[string]interface{}) error {
 	if children, err := getChildrenAsset(child.ID); err == nil {
 		security := make([]interface{}, 0, len(children))
 		for _, c := range children {
 			s := make(map[string]interface{})
-			extractSecurityRequirementScopes(&c, s)
+			if c.AssetType == AssetTypes["JSON Element"] {
+				// AsyncAPI 3.0 security scheme or its ref
+				if ok := setComponentRef(&c, s); !ok {
+					extractSecuritySchemeAsset(&c, s)
+					s = s[c.Label].(map[string]interface{})
+				}
+			} else {
+				extractSecurityRequirementScopes(&c, s)
+			}
 			security = append(security, s)
 		}
 		parent["security"] = security
@@ -1211,13 +1923,13 @@ func extractChannelsAsset(child *Asset, parent map[string]interface{}) error {
 
 	if children, err := getChildrenAsset(child.ID); err == nil {
 		for _, c := range children {
-			extractChannelAsset(&c, channels)
+			extractChannelAsset(&c, channels, false)
 		}
 	}
 	return nil
 }
 
-// set $ref in a node if an asset has data type ref to a component.
+// set $ref in a node if an asset has data type ref to a component or an external definition.
 // return true if $ref is set
 func setComponentRef(asset *Asset, node map[string]interface{}) bool {
 	dataType := ""
@@ -1226,23 +1938,28 @@ func setComponentRef(asset *Asset, node map[string]interface{}) bool {
 			dataType = getTypeRef(tid)
 		}
 	}
-	if strings.HasPrefix(dataType, "#/components") {
+	if strings.HasPrefix(dataType, "#/components") || isExternalRef(dataType) {
 		node["$ref"] = dataType
 		return true
 	}
 	return false
 }
 
-func extractChannelAsset(child *Asset, parent map[string]interface{}) error {
+func extractChannelAsset(child *Asset, parent map[string]interface{}, isComponent bool) error {
 	channel := make(map[string]interface{})
 	parent[child.Label] = channel
 
-	if ok := setComponentRef(child, channel); ok {
-		return nil
+	if !isComponent {
+		if ok := setComponentRef(child, channel); ok {
+			return nil
+		}
 	}
 	if len(child.Description) > 0 {
 		channel["description"] = child.Description
 	}
+	if len(child.Comment) > 0 {
+		extractComment(child.Comment, channel)
+	}
 
 	if children, err := getChildrenAsset(child.ID); err == nil {
 		for _, c := range children {
@@ -1251,6 +1968,8 @@ func extractChannelAsset(child *Asset, parent map[string]interface{}) error {
 				extractParametersAsset(&c, channel)
 			case "subscribe", "publish":
 				extractOperationAsset(&c, channel)
+			case "bindings":
+				extractBindingsAsset(&c, channel)
 			default:
 				fmt.Printf("channel child type %s is not implemented\n", c.Label)
 			}
@@ -1283,13 +2002,18 @@ func extractParameterAsset(child *Asset, parent map[string]interface{}, isCompon
 	if len(child.Description) > 0 {
 		parameter["description"] = child.Description
 	}
+	if len(child.Comment) > 0 {
+		extractComment(child.Comment, parameter)
+	}
 	if children, err := getChildrenAsset(child.ID); err == nil {
 		for _, c := range children {
 			switch c.Label {
-			case "location":
+			case "location", "default":
 				extractSimpleAsset(&c, parameter)
 			case "schema":
 				extractSchemaAsset(&c, parameter, false)
+			case "enum":
+				extractEnumAsset(&c, parameter)
 			default:
 				fmt.Printf("channel child type %s is not implemented\n", c.Label)
 			}
@@ -1314,16 +2038,63 @@ func extractSchemaAsset(child *Asset, parent map[string]interface{}, isComponent
 		extractComment(child.Comment, schema)
 	}
 
-	if children, err := getChildrenAsset(child.ID); err == nil && children != nil && len(children) > 0 {
+	if children, err := getChildrenAsset(child.ID); err == nil {
 		properties := make(map[string]interface{})
-		schema["properties"] = properties
 		for _, c := range children {
-			extractSchemaAsset(&c, properties, false)
+			switch {
+			case c.AssetType == AssetTypes["JSON Property"]:
+				extractSchemaAsset(&c, properties, false)
+			case c.Label == "items" && isSchemaList(&c), c.Label == "allOf", c.Label == "anyOf", c.Label == "oneOf":
+				extractSchemaListAsset(&c, schema)
+			case c.Label == "items", c.Label == "not":
+				extractSchemaAsset(&c, schema, false)
+			default:
+				extractSchemaAsset(&c, properties, false)
+			}
+		}
+		if len(properties) > 0 {
+			schema["properties"] = properties
+		}
+	}
+	return nil
+}
+
+// returns true if an asset is a list of subschemas. A schema of array type has its type in comment,
+// while a list of subschemas has only array data type.
+func isSchemaList(asset *Asset) bool {
+	return len(asset.Comment) == 0 && len(asset.Description) == 0 && dataTypeName(asset) == "array"
+}
+
+func extractSchemaListAsset(child *Asset, parent map[string]interface{}) error {
+	children, err := getChildrenAsset(child.ID)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to fetch subschemas of %s", child.Label)
+	}
+	// children are named by their index in the list
+	schemas := make([]interface{}, len(children))
+	for _, c := range children {
+		i, err := strconv.Atoi(c.Label)
+		if err != nil || i < 0 || i >= len(children) {
+			return errors.Errorf("Invalid index %s of %s", c.Label, child.Label)
 		}
+		schema := make(map[string]interface{})
+		extractSchemaAsset(&c, schema, false)
+		schemas[i] = schema[c.Label]
 	}
+	parent[child.Label] = schemas
 	return nil
 }
 
+// returns name of the data type of an asset, or empty string if it is not typed
+func dataTypeName(asset *Asset) string {
+	if len(asset.AssetDataType) > 0 {
+		if tid, err := strconv.Atoi(asset.AssetDataType); err == nil {
+			return getTypeRef(tid)
+		}
+	}
+	return ""
+}
+
 func extractOperationAsset(child *Asset, parent map[string]interface{}) error {
 	operation := make(map[string]interface{})
 	parent[child.Label] = operation
@@ -1338,6 +2109,10 @@ func extractOperationAsset(child *Asset, parent map[string]interface{}) error {
 	if children, err := getChildrenAsset(child.ID); err == nil {
 		for _, c := range children {
 			switch c.Label {
+			case "operationId":
+				extractSimpleAsset(&c, operation)
+			case "security":
+				extractSecurityRequirementAsset(&c, operation)
 			case "tags":
 				extractTagsAsset(&c, operation)
 			case "externalDocs":
@@ -1346,6 +2121,8 @@ func extractOperationAsset(child *Asset, parent map[string]interface{}) error {
 				extractMessageAsset(&c, operation, false)
 			case "traits":
 				extractTraitsAsset(&c, operation, "operation")
+			case "bindings":
+				extractBindingsAsset(&c, operation)
 			default:
 				fmt.Printf("operation child type %s is not implemented\n", c.Label)
 			}
@@ -1378,8 +2155,16 @@ func extractMessageAsset(child *Asset, parent map[string]interface{}, isComponen
 				extractTagsAsset(&c, message)
 			case "externalDocs":
 				extractExternalDocsAsset(&c, message)
-			case "payload":
+			case "payload", "headers":
 				extractSchemaAsset(&c, message, false)
+			case "correlationId":
+				extractCorrelationIDAsset(&c, message, false)
+			case "bindings":
+				extractBindingsAsset(&c, message)
+			case "messageId":
+				extractSimpleAsset(&c, message)
+			case "examples":
+				extractMessageExamplesAsset(&c, message)
 			case "traits":
 				extractTraitsAsset(&c, message, "message")
 			default:
@@ -1390,6 +2175,54 @@ func extractMessageAsset(child *Asset, parent map[string]interface{}, isComponen
 	return nil
 }
 
+func extractMessageExamplesAsset(child *Asset, parent map[string]interface{}) error {
+	if len(child.Comment) > 0 {
+		// examples imported as a single JSON value
+		return extractSimpleAsset(child, parent)
+	}
+	children, err := getChildrenAsset(child.ID)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to fetch examples of asset %d", child.ID)
+	}
+	examples := make([]interface{}, 0, len(children))
+	for _, c := range children {
+		examples = append(examples, extractMessageExampleAsset(&c))
+	}
+	parent["examples"] = examples
+	return nil
+}
+
+func extractMessageExampleAsset(child *Asset) interface{} {
+	var value interface{}
+	if len(child.Comment) > 0 {
+		if err := json.Unmarshal([]byte(child.Comment), &value); err != nil {
+			fmt.Printf("Failed to deserialize example %s: %v\n", child.Label, err)
+		}
+	}
+	example, ok := value.(map[string]interface{})
+	if value != nil && !ok {
+		// example that is not an object
+		return value
+	}
+	if example == nil {
+		example = make(map[string]interface{})
+	}
+	if len(child.Description) > 0 {
+		example["summary"] = child.Description
+	}
+	if children, err := getChildrenAsset(child.ID); err == nil {
+		for _, c := range children {
+			switch c.Label {
+			case "headers", "payload":
+				extractSimpleAsset(&c, example)
+			default:
+				fmt.Printf("message example child type %s is not implemented\n", c.Label)
+			}
+		}
+	}
+	return example
+}
+
 func extractTraitsAsset(child *Asset, parent map[string]interface{}, traitType string) error {
 	if children, err := getChildrenAsset(child.ID); err == nil {
 		traits := make([]interface{}, 0, len(children))
@@ -1427,12 +2260,16 @@ func extractOperationTraitAsset(child *Asset, trait map[string]interface{}, isCo
 	if children, err := getChildrenAsset(child.ID); err == nil {
 		for _, c := range children {
 			switch c.Label {
+			case "operationId":
+				extractSimpleAsset(&c, trait)
+			case "security":
+				extractSecurityRequirementAsset(&c, trait)
 			case "tags":
 				extractTagsAsset(&c, trait)
 			case "externalDocs":
 				extractExternalDocsAsset(&c, trait)
 			case "bindings":
-				extractSimpleAsset(&c, trait)
+				extractBindingsAsset(&c, trait)
 			default:
 				fmt.Printf("operation trait child type %s is not implemented\n", c.Label)
 			}
@@ -1464,6 +2301,14 @@ func extractMessageTraitAsset(child *Asset, trait map[string]interface{}, isComp
 				extractExternalDocsAsset(&c, trait)
 			case "headers":
 				extractSchemaAsset(&c, trait, false)
+			case "correlationId":
+				extractCorrelationIDAsset(&c, trait, false)
+			case "bindings":
+				extractBindingsAsset(&c, trait)
+			case "messageId":
+				extractSimpleAsset(&c, trait)
+			case "examples":
+				extractMessageExamplesAsset(&c, trait)
 			default:
 				fmt.Printf("message trait child type %s is not implemented\n", c.Label)
 			}
@@ -1472,6 +2317,34 @@ func extractMessageTraitAsset(child *Asset, trait map[string]interface{}, isComp
 	return nil
 }
 
+func extractCorrelationIDAsset(child *Asset, parent map[string]interface{}, isComponent bool) error {
+	correlationID := make(map[string]interface{})
+	parent[child.Label] = correlationID
+
+	if !isComponent {
+		if ok := setComponentRef(child, correlationID); ok {
+			return nil
+		}
+	}
+	if len(child.Description) > 0 {
+		correlationID["description"] = child.Description
+	}
+	if len(child.Comment) > 0 {
+		extractComment(child.Comment, correlationID)
+	}
+
+	if children, err := getChildrenAsset(child.ID); err == nil {
+		for _, c := range children {
+			if c.Label == "location" {
+				extractSimpleAsset(&c, correlationID)
+			} else {
+				fmt.Printf("correlation ID child type %s is not implemented\n", c.Label)
+			}
+		}
+	}
+	return nil
+}
+
 func extractComponentsAsset(child *Asset, parent map[string]interface{}) error {
 	components := make(map[string]interface{})
 	parent[child.Label] = components
@@ -1506,6 +2379,28 @@ func extractComponentsAsset(child *Asset, parent map[string]interface{}) error {
 				trait := make(map[string]interface{})
 				category[c.Label] = trait
 				extractMessageTraitAsset(&c, trait, true)
+			case "correlationIds":
+				extractCorrelationIDAsset(&c, category, true)
+			case "serverVariables":
+				extractServerVariableAsset(&c, category, true)
+			case "servers":
+				extractServerAsset(&c, category, true)
+			case "channels":
+				if isAsyncAPI3() {
+					extractChannel3Asset(&c, category, jsonPointer("components", cat.Label, c.Label))
+				} else {
+					extractChannelAsset(&c, category, true)
+				}
+			case "operations":
+				extractOperation3Asset(&c, category, jsonPointer("components", cat.Label, c.Label))
+			case "replies":
+				extractOperationReplyAsset(&c, category, true)
+			case "replyAddresses":
+				extractCorrelationIDAsset(&c, category, true)
+			case "tags", "externalDocs":
+				extractComponentValueAsset(&c, category)
+			case "serverBindings", "channelBindings", "operationBindings", "messageBindings":
+				extractComponentBindingsAsset(&c, category)
 			default:
 				fmt.Printf("component type %s is not implemented\n", cat.Label)
 			}
@@ -1571,17 +2466,18 @@ func extractOAuthFlowScopesAsset(child *Asset, parent map[string]interface{}) er
 	return nil
 }
 
+// returns the ref of a data type ID
 func getTypeRef(id int) string {
-	if result, ok := AssetDataTypeIDs[id]; ok {
-		return result
+	if result, ok := cachedTypeRef(id); ok {
+		return localRef(result)
 	}
 	dataType, err := getAssetDataTypeByID(id)
 	if err != nil {
 		return ""
 	}
 	fmt.Printf("cache dataType %d => %s\n", id, dataType.Label)
-	AssetDataTypeIDs[id] = dataType.Label
-	return dataType.Label
+	cacheTypeRef(id, dataType.Label)
+	return localRef(dataType.Label)
 }
 
 func extractComment(comment string, parent map[string]interface{}) error {
@@ -0,0 +1,581 @@
+package cmd
+
+/*
+Copyright © 2020 Yueming Xu <yxu@tibco.com>
+This file is subject to the license terms contained in the license file that is distributed with this file.
+*/
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// AsyncAPI 3.0 defines channels and operations as separate root objects, and operations refer to channels and
+// messages by JSON pointers, e.g., #/channels/userSignup/messages/userSignedUp.  Channels, their messages and
+// operations are typed by their own JSON pointers, so the referring assets share the data type of the definitions.
+
+// spec version of the AsyncAPI spec being imported or exported
+var asyncAPIVersion string
+
+func isAsyncAPI3() bool {
+	return strings.HasPrefix(asyncAPIVersion, "3.")
+}
+
+// refScope is the root asset of the spec being imported, exported or cleaned
+var refScope string
+
+// returns data type name of a ref. A local ref of a definition that is not a component, e.g., #/channels/lightMeasured,
+// is scoped by the spec root, e.g., streetlights#/channels/lightMeasured, so specs that define the same JSON pointer
+// do not share the data type.
+func scopedTypeName(ref string) string {
+	if strings.HasPrefix(ref, "#/") && !strings.HasPrefix(ref, "#/components/") {
+		return refScope + ref
+	}
+	return ref
+}
+
+// returns the ref of a data type name, i.e., a local ref without the scope of the spec root
+func localRef(dataType string) string {
+	if len(refScope) > 0 && strings.HasPrefix(dataType, refScope+"#/") {
+		return strings.TrimPrefix(dataType, refScope)
+	}
+	return dataType
+}
+
+// returns data type ID of the $ref of a node, or of the JSON pointer of the node if it is not a ref
+func definitionDataType(node interface{}, path string) (int, error) {
+	if ref := getString(node, "#/$ref"); len(ref) > 0 {
+		return setRef(ref)
+	}
+	return setRef(path)
+}
+
+// returns true if an asset is typed by a ref other than its own JSON pointer
+func isReference(asset *Asset, path string) bool {
+	dataType := dataTypeName(asset)
+	return (strings.HasPrefix(dataType, "#/") || isExternalRef(dataType)) && dataType != path
+}
+
+func createChannels3Asset(channels interface{}, parent int) error {
+	cm, ok := channels.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("channels type %T is not a map", channels)
+	}
+	asset := Asset{
+		Name:                    "channels",
+		Label:                   "channels",
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+
+	var g importGroup
+	for k, v := range cm {
+		k, v := k, v
+		g.run(func() error {
+			path := jsonPointer("channels", k)
+			tid, err := definitionDataType(v, path)
+			if err != nil {
+				return err
+			}
+			return createChannel3Asset(k, v, tid, pid, path)
+		}, k)
+	}
+	return g.wait()
+}
+
+// create AsyncAPI 3.0 channel with child assets of address, messages, servers, parameters, tags, externalDocs and bindings
+func createChannel3Asset(name string, channel interface{}, tid int, parent int, path string) error {
+	props, ok := channel.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("No properties for channel %s", name)
+	}
+	comment := extractExtraProperties(props, []string{"$ref", "address", "description", "messages", "servers", "parameters", "tags", "externalDocs", "bindings"})
+	asset := Asset{
+		Name:                    name,
+		Label:                   name,
+		Description:             getString(channel, "#/description"),
+		Comment:                 comment,
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	if tid > 0 {
+		asset.AssetDataType = strconv.Itoa(tid)
+	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+
+	var errs specErrors
+	if address := getString(channel, "#/address"); len(address) > 0 {
+		_, err := createSimpleAsset("address", address, pid, "string")
+		errs.add(err, "address")
+	}
+	if messages, ok := props["messages"]; ok {
+		errs.add(createChannelMessagesAsset(messages, pid, path+"/messages"), "messages")
+	}
+	if servers, ok := props["servers"]; ok {
+		errs.add(createRefListAsset("servers", servers, pid), "servers")
+	}
+	if params, ok := props["parameters"]; ok {
+		errs.add(createParametersAsset(params, pid), "parameters")
+	}
+	if tags, ok := props["tags"]; ok {
+		errs.add(createTagsAsset(tags, pid), "tags")
+	}
+	if externalDocs, ok := props["externalDocs"]; ok {
+		errs.add(createExternalDocsAsset(externalDocs, pid), "externalDocs")
+	}
+	if bindings, ok := props["bindings"]; ok {
+		errs.add(createBindingsAsset(bindings, "channel", pid), "bindings")
+	}
+	return errs.result()
+}
+
+// create messages of a channel, which are typed by their JSON pointers under the channel path
+func createChannelMessagesAsset(messages interface{}, parent int, path string) error {
+	mm, ok := messages.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("messages type %T is not a map", messages)
+	}
+	asset := Asset{
+		Name:                    "messages",
+		Label:                   "messages",
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+
+	var errs specErrors
+	for k, v := range mm {
+		tid, err := definitionDataType(v, path+strings.TrimPrefix(jsonPointer(k), "#"))
+		if err == nil {
+			err = createMessageAsset(k, v, tid, pid)
+		}
+		errs.add(err, k)
+	}
+	return errs.result()
+}
+
+func createOperationsAsset(operations interface{}, parent int) error {
+	om, ok := operations.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("operations type %T is not a map", operations)
+	}
+	asset := Asset{
+		Name:                    "operations",
+		Label:                   "operations",
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+
+	var g importGroup
+	for k, v := range om {
+		k, v := k, v
+		g.run(func() error {
+			tid, err := definitionDataType(v, jsonPointer("operations", k))
+			if err != nil {
+				return err
+			}
+			return createOperation3Asset(k, v, tid, pid)
+		}, k)
+	}
+	return g.wait()
+}
+
+// create AsyncAPI 3.0 operation with child assets of action, channel and messages refs, reply, traits,
+// and security, tags, externalDocs and bindings
+func createOperation3Asset(name string, operation interface{}, tid int, parent int) error {
+	op, ok := operation.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("operation %s type %T is not a map", name, operation)
+	}
+	comment := extractExtraProperties(op, []string{"$ref", "action", "channel", "messages", "reply", "description", "security", "tags", "externalDocs", "traits", "bindings"})
+	asset := Asset{
+		Name:                    name,
+		Label:                   name,
+		Description:             getString(operation, "#/description"),
+		Comment:                 comment,
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	if tid > 0 {
+		asset.AssetDataType = strconv.Itoa(tid)
+	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+
+	var errs specErrors
+	if action := getString(operation, "#/action"); len(action) > 0 {
+		if action != "send" && action != "receive" {
+			fmt.Printf("operation %s action %s is not send or receive\n", name, action)
+		}
+		_, err := createSimpleAsset("action", action, pid, "string")
+		errs.add(err, "action")
+	}
+	if channel, ok := op["channel"]; ok {
+		errs.add(createRefAsset("channel", channel, pid), "channel")
+	}
+	if messages, ok := op["messages"]; ok {
+		errs.add(createRefListAsset("messages", messages, pid), "messages")
+	}
+	if reply, ok := op["reply"]; ok {
+		tid, err := refDataType(reply)
+		if err == nil {
+			err = createOperationReplyAsset("reply", reply, tid, pid)
+		}
+		errs.add(err, "reply")
+	}
+	if traits, ok := op["traits"]; ok {
+		errs.add(createOperationTraitsAsset(traits, pid), "traits")
+	}
+	errs.add(createOperationDetailAssets(operation, pid))
+	return errs.result()
+}
+
+// operation reply contains address, and refs of channel and messages
+func createOperationReplyAsset(name string, reply interface{}, tid int, parent int) error {
+	rm, ok := reply.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("reply %s type %T is not a map", name, reply)
+	}
+	asset := Asset{
+		Name:                    name,
+		Label:                   name,
+		Comment:                 extractExtraProperties(rm, []string{"$ref", "address", "channel", "messages"}),
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	if tid > 0 {
+		asset.AssetDataType = strconv.Itoa(tid)
+	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+
+	var errs specErrors
+	if address, ok := rm["address"]; ok {
+		// reply address has the same description and location as a correlation ID
+		tid, err := refDataType(address)
+		if err == nil {
+			err = createCorrelationIDAsset("address", address, tid, pid)
+		}
+		errs.add(err, "address")
+	}
+	if channel, ok := rm["channel"]; ok {
+		errs.add(createRefAsset("channel", channel, pid), "channel")
+	}
+	if messages, ok := rm["messages"]; ok {
+		errs.add(createRefListAsset("messages", messages, pid), "messages")
+	}
+	return errs.result()
+}
+
+// create an asset that contains only a $ref, which is stored as its data type
+func createRefAsset(name string, node interface{}, parent int) error {
+	tid, err := refDataType(node)
+	if err != nil {
+		return err
+	}
+	if tid == 0 {
+		return errors.Errorf("%s does not contain $ref", name)
+	}
+	asset := Asset{
+		Name:                    name,
+		Label:                   name,
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		AssetDataType:           strconv.Itoa(tid),
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	_, err = createAsset(asset)
+	return err
+}
+
+// create an array asset for a list of refs, each is named by its index in the list
+func createRefListAsset(name string, list interface{}, parent int) error {
+	refs, ok := list.([]interface{})
+	if !ok {
+		return errors.Errorf("%s type %T is not an array", name, list)
+	}
+	asset := Asset{
+		Name:                    name,
+		Label:                   name,
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		AssetDataType:           strconv.Itoa(dataTypeID("array")),
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+
+	var errs specErrors
+	for i, ref := range refs {
+		errs.add(createRefAsset(strconv.Itoa(i), ref, pid), strconv.Itoa(i))
+	}
+	return errs.result()
+}
+
+func extractChannels3Asset(child *Asset, parent map[string]interface{}) error {
+	channels := make(map[string]interface{})
+	parent["channels"] = channels
+
+	if children, err := getChildrenAsset(child.ID); err == nil {
+		for _, c := range children {
+			extractChannel3Asset(&c, channels, jsonPointer("channels", c.Label))
+		}
+	}
+	return nil
+}
+
+func extractChannel3Asset(child *Asset, parent map[string]interface{}, path string) error {
+	channel := make(map[string]interface{})
+	parent[child.Label] = channel
+
+	if isReference(child, path) {
+		channel["$ref"] = dataTypeName(child)
+		return nil
+	}
+	if len(child.Description) > 0 {
+		channel["description"] = child.Description
+	}
+	if len(child.Comment) > 0 {
+		extractComment(child.Comment, channel)
+	}
+
+	if children, err := getChildrenAsset(child.ID); err == nil {
+		for _, c := range children {
+			switch c.Label {
+			case "address":
+				extractSimpleAsset(&c, channel)
+			case "messages":
+				extractChannelMessagesAsset(&c, channel, path+"/messages")
+			case "servers":
+				extractRefListAsset(&c, channel)
+			case "parameters":
+				extractParametersAsset(&c, channel)
+			case "tags":
+				extractTagsAsset(&c, channel)
+			case "externalDocs":
+				extractExternalDocsAsset(&c, channel)
+			case "bindings":
+				extractBindingsAsset(&c, channel)
+			default:
+				fmt.Printf("channel child type %s is not implemented\n", c.Label)
+			}
+		}
+	}
+	return nil
+}
+
+func extractChannelMessagesAsset(child *Asset, parent map[string]interface{}, path string) error {
+	messages := make(map[string]interface{})
+	parent["messages"] = messages
+
+	if children, err := getChildrenAsset(child.ID); err == nil {
+		for _, c := range children {
+			if isReference(&c, path+strings.TrimPrefix(jsonPointer(c.Label), "#")) {
+				messages[c.Label] = map[string]interface{}{"$ref": dataTypeName(&c)}
+			} else {
+				extractMessageAsset(&c, messages, true)
+			}
+		}
+	}
+	return nil
+}
+
+func extractOperationsAsset(child *Asset, parent map[string]interface{}) error {
+	operations := make(map[string]interface{})
+	parent["operations"] = operations
+
+	if children, err := getChildrenAsset(child.ID); err == nil {
+		for _, c := range children {
+			extractOperation3Asset(&c, operations, jsonPointer("operations", c.Label))
+		}
+	}
+	return nil
+}
+
+func extractOperation3Asset(child *Asset, parent map[string]interface{}, path string) error {
+	operation := make(map[string]interface{})
+	parent[child.Label] = operation
+
+	if isReference(child, path) {
+		operation["$ref"] = dataTypeName(child)
+		return nil
+	}
+	if len(child.Description) > 0 {
+		operation["description"] = child.Description
+	}
+	if len(child.Comment) > 0 {
+		extractComment(child.Comment, operation)
+	}
+
+	if children, err := getChildrenAsset(child.ID); err == nil {
+		for _, c := range children {
+			switch c.Label {
+			case "action":
+				extractSimpleAsset(&c, operation)
+			case "channel":
+				extractRefAsset(&c, operation)
+			case "messages":
+				extractRefListAsset(&c, operation)
+			case "reply":
+				extractOperationReplyAsset(&c, operation, false)
+			case "traits":
+				extractTraitsAsset(&c, operation, "operation")
+			case "security":
+				extractSecurityRequirementAsset(&c, operation)
+			case "tags":
+				extractTagsAsset(&c, operation)
+			case "externalDocs":
+				extractExternalDocsAsset(&c, operation)
+			case "bindings":
+				extractBindingsAsset(&c, operation)
+			default:
+				fmt.Printf("operation child type %s is not implemented\n", c.Label)
+			}
+		}
+	}
+	return nil
+}
+
+func extractOperationReplyAsset(child *Asset, parent map[string]interface{}, isComponent bool) error {
+	reply := make(map[string]interface{})
+	parent[child.Label] = reply
+
+	if !isComponent {
+		if ok := setComponentRef(child, reply); ok {
+			return nil
+		}
+	}
+	if len(child.Comment) > 0 {
+		extractComment(child.Comment, reply)
+	}
+
+	if children, err := getChildrenAsset(child.ID); err == nil {
+		for _, c := range children {
+			switch c.Label {
+			case "address":
+				extractCorrelationIDAsset(&c, reply, false)
+			case "channel":
+				extractRefAsset(&c, reply)
+			case "messages":
+				extractRefListAsset(&c, reply)
+			default:
+				fmt.Printf("reply child type %s is not implemented\n", c.Label)
+			}
+		}
+	}
+	return nil
+}
+
+func extractRefAsset(child *Asset, parent map[string]interface{}) error {
+	ref := dataTypeName(child)
+	if len(ref) == 0 {
+		return errors.Errorf("%s does not have a ref data type", child.Label)
+	}
+	parent[child.Label] = map[string]interface{}{"$ref": ref}
+	return nil
+}
+
+func extractRefListAsset(child *Asset, parent map[string]interface{}) error {
+	children, err := getChildrenAsset(child.ID)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to fetch refs of %s", child.Label)
+	}
+	// children are named by their index in the list
+	refs := make([]interface{}, len(children))
+	for _, c := range children {
+		i, err := strconv.Atoi(c.Label)
+		if err != nil || i < 0 || i >= len(children) {
+			return errors.Errorf("Invalid index %s of %s", c.Label, child.Label)
+		}
+		refs[i] = map[string]interface{}{"$ref": dataTypeName(&c)}
+	}
+	parent[child.Label] = refs
+	return nil
+}
+
+// delete data types of AsyncAPI 3.0 channels, channel messages and operations
+func cleanAsyncAPI3DataTypes(spec interface{}) error {
+	if !strings.HasPrefix(getString(spec, "#/asyncapi"), "3.") {
+		return nil
+	}
+	var paths []string
+	if channels, ok := getRef(spec, "#/channels").(map[string]interface{}); ok {
+		for k, v := range channels {
+			paths = append(paths, jsonPointer("channels", k))
+			if messages, ok := getRef(v, "#/messages").(map[string]interface{}); ok {
+				for m := range messages {
+					paths = append(paths, jsonPointer("channels", k, "messages", m))
+				}
+			}
+		}
+	}
+	if channels, ok := getRef(spec, "#/components/channels").(map[string]interface{}); ok {
+		for k, v := range channels {
+			if messages, ok := getRef(v, "#/messages").(map[string]interface{}); ok {
+				for m := range messages {
+					paths = append(paths, jsonPointer("components", "channels", k, "messages", m))
+				}
+			}
+		}
+	}
+	if operations, ok := getRef(spec, "#/operations").(map[string]interface{}); ok {
+		for k := range operations {
+			paths = append(paths, jsonPointer("operations", k))
+		}
+	}
+
+	for _, p := range paths {
+		name := scopedTypeName(p)
+		if tid := getAssetDataType(name); tid > 0 {
+			inUse, err := sharedDataTypeInUse(tid, name)
+			if err != nil {
+				return err
+			}
+			if inUse {
+				continue
+			}
+			fmt.Printf("cleanup data type %d -> %s\n", tid, p)
+			if err := deleteAssetDataType(tid); err != nil {
+				return errors.Wrapf(err, "Failed to delete data type %d", tid)
+			}
+		}
+	}
+	return nil
+}
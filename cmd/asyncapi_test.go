@@ -0,0 +1,479 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageDetailsRoundTrip(t *testing.T) {
+	fake := startFakeTCMD(t)
+	root = "message-test"
+
+	doc := `{
+        "asyncapi": "2.0.0",
+        "info": {"title": "Message test", "version": "1.0.0"},
+        "channels": {
+            "light/measured": {
+                "publish": {
+                    "message": {"$ref": "#/components/messages/lightMeasured"}
+                }
+            }
+        },
+        "components": {
+            "messages": {
+                "lightMeasured": {
+                    "name": "lightMeasured",
+                    "headers": {
+                        "type": "object",
+                        "properties": {"my-app-header": {"type": "integer"}}
+                    },
+                    "correlationId": {"$ref": "#/components/correlationIds/default"},
+                    "bindings": {"kafka": {"key": {"type": "string"}}},
+                    "examples": [
+                        {"payload": {"lumens": 3}},
+                        {"name": "bright", "summary": "A bright light", "headers": {"my-app-header": 12}, "payload": {"lumens": 1000}}
+                    ],
+                    "payload": {"type": "object"}
+                }
+            },
+            "correlationIds": {
+                "default": {
+                    "description": "Default correlation ID",
+                    "location": "$message.header#/correlationId"
+                }
+            }
+        }
+    }`
+	var spec map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(doc), &spec), "test spec should be valid JSON")
+
+	assert.NoError(t, importAPISpec(spec), "import should not return error")
+	exported, err := exportAPISpec(root)
+	assert.NoError(t, err, "export should not return error")
+
+	message := getRef(exported, "#/components/messages/lightMeasured")
+	assert.Equal(t, getRef(spec, "#/components/messages/lightMeasured/headers"), getRef(message, "#/headers"), "headers do not match")
+	assert.Equal(t, "#/components/correlationIds/default", getString(message, "#/correlationId/$ref"), "correlationId ref does not match")
+	assert.Equal(t, getRef(spec, "#/components/messages/lightMeasured/bindings"), getRef(message, "#/bindings"), "bindings do not match")
+	assert.Equal(t, getRef(spec, "#/components/messages/lightMeasured/examples"), getRef(message, "#/examples"), "examples do not match")
+	assert.Equal(t, getRef(spec, "#/components/correlationIds"), getRef(exported, "#/components/correlationIds"), "correlationIds do not match")
+
+	examples := make(map[string][]string)
+	for _, a := range fake.assets {
+		if p, ok := fake.assets[assetParentID(&a)]; ok && a.Parent != "" && p.Name == "examples" {
+			for _, c := range fake.assets {
+				if c.Parent == strconv.Itoa(a.ID) {
+					examples[a.Name] = append(examples[a.Name], c.Name)
+				}
+			}
+		}
+	}
+	assert.ElementsMatch(t, []string{"payload"}, examples["example-0"], "example without name should be a child asset")
+	assert.ElementsMatch(t, []string{"headers", "payload"}, examples["bright"], "example should be named by its name")
+}
+
+func TestBindingsRoundTrip(t *testing.T) {
+	fake := startFakeTCMD(t)
+	root = "bindings-test"
+
+	doc := `{
+        "asyncapi": "2.0.0",
+        "servers": {
+            "production": {
+                "url": "localhost:1883",
+                "protocol": "mqtt",
+                "bindings": {
+                    "mqtt": {
+                        "clientId": "guest",
+                        "cleanSession": true,
+                        "keepAlive": 60,
+                        "lastWill": {"topic": "/last-wills", "qos": 2, "retain": false}
+                    }
+                }
+            }
+        },
+        "channels": {
+            "lightMeasured": {
+                "bindings": {
+                    "amqp": {
+                        "is": "routingKey",
+                        "exchange": {"name": "lights", "type": "topic", "durable": true}
+                    },
+                    "ws": {"method": "GET", "query": {"type": "object", "properties": {"token": {"type": "string"}}}}
+                },
+                "publish": {
+                    "bindings": {
+                        "mqtt": {"qos": 1, "retain": true},
+                        "kafka": {"groupId": {"type": "string", "enum": ["lights"]}, "clientId": "my-app-id"}
+                    },
+                    "message": {
+                        "bindings": {
+                            "kafka": {"key": {"type": "string", "enum": ["myKey"]}, "bindingVersion": "0.1.0"},
+                            "amqp": {"contentEncoding": "gzip", "cc": ["user.logs"]}
+                        },
+                        "payload": {"type": "object"}
+                    }
+                }
+            }
+        }
+    }`
+	var spec map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(doc), &spec), "test spec should be valid JSON")
+
+	assert.NoError(t, importAPISpec(spec), "import should not return error")
+	exported, err := exportAPISpec(root)
+	assert.NoError(t, err, "export should not return error")
+
+	for _, path := range []string{
+		"#/servers/production/bindings",
+		"#/channels/lightMeasured/bindings",
+		"#/channels/lightMeasured/publish/bindings",
+		"#/channels/lightMeasured/publish/message/bindings",
+	} {
+		assert.Equal(t, getRef(spec, path), getRef(exported, path), "bindings do not match at %s", path)
+	}
+
+	// kafka key schema is a child asset of a binding typed as kafkaMessageBinding
+	tid := AssetDataTypes["kafkaMessageBinding"]
+	assert.True(t, tid > 0, "kafka message binding data type should be created")
+	var keys []Asset
+	for _, a := range fake.assets {
+		if a.Name == "key" && a.Parent != "" && fake.assets[assetParentID(&a)].AssetDataType == strconv.Itoa(tid) {
+			keys = append(keys, a)
+		}
+	}
+	assert.Equal(t, 1, len(keys), "kafka key schema should be found by binding data type")
+}
+
+func TestServersRoundTrip(t *testing.T) {
+	startFakeTCMD(t)
+	root = "servers-test"
+
+	doc := `{
+        "asyncapi": "2.0.0",
+        "servers": {
+            "production": {
+                "url": "localhost:{port}/v{version}",
+                "protocol": "mqtt",
+                "protocolVersion": "3.1.1",
+                "description": "Test broker",
+                "x-store": ":memory:",
+                "variables": {
+                    "port": {
+                        "description": "Secure connection (TLS) is available through port 8883.",
+                        "default": "1883",
+                        "enum": ["1883", "8883"]
+                    },
+                    "version": {
+                        "default": 2,
+                        "enum": [1, 2.5, true, "latest"]
+                    }
+                }
+            },
+            "development": {
+                "url": "localhost:1883",
+                "protocol": "mqtt"
+            }
+        }
+    }`
+	var spec map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(doc), &spec), "test spec should be valid JSON")
+
+	assert.NoError(t, importAPISpec(spec), "import should not return error for server without description")
+	exported, err := exportAPISpec(root)
+	assert.NoError(t, err, "export should not return error")
+	assert.Equal(t, spec["servers"], getRef(exported, "#/servers"), "servers do not match")
+	assert.Equal(t, []string{"port", "version"}, urlTemplateVariables("localhost:{port}/v{version}"), "url variables do not match")
+}
+
+func TestSchemaItemsRoundTrip(t *testing.T) {
+	fake := startFakeTCMD(t)
+	root = "schema-test"
+
+	doc := `{
+        "asyncapi": "2.0.0",
+        "components": {
+            "schemas": {
+                "lumens": {"type": "integer", "minimum": 0},
+                "sentAt": {"type": "string", "format": "date-time"},
+                "lightMeasuredPayload": {
+                    "type": "array",
+                    "items": {"$ref": "#/components/schemas/lumens"}
+                },
+                "tuple": {
+                    "type": "array",
+                    "items": [{"type": "string"}, {"$ref": "#/components/schemas/sentAt"}]
+                },
+                "composite": {
+                    "allOf": [
+                        {"$ref": "#/components/schemas/sentAt"},
+                        {"type": "object", "properties": {"items": {"type": "array", "items": {"type": "string"}}}}
+                    ],
+                    "oneOf": [{"type": "string"}, {"type": "integer"}],
+                    "anyOf": [{"$ref": "#/components/schemas/lumens"}],
+                    "not": {"type": "boolean"}
+                }
+            }
+        }
+    }`
+	var spec map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(doc), &spec), "test spec should be valid JSON")
+
+	assert.NoError(t, importAPISpec(spec), "import should not return error")
+	exported, err := exportAPISpec(root)
+	assert.NoError(t, err, "export should not return error")
+	assert.Equal(t, getRef(spec, "#/components/schemas"), getRef(exported, "#/components/schemas"), "schemas do not match")
+
+	// items of lightMeasuredPayload uses the shared data type of lumens
+	tid := strconv.Itoa(AssetDataTypes["#/components/schemas/lumens"])
+	found := false
+	for _, a := range fake.assets {
+		if a.Name == "items" && a.AssetDataType == tid && fake.assets[assetParentID(&a)].Name == "lightMeasuredPayload" {
+			found = true
+		}
+	}
+	assert.True(t, found, "items should refer to component data type")
+}
+
+func TestAsyncAPI26RoundTrip(t *testing.T) {
+	startFakeTCMD(t)
+	root = "asyncapi26-test"
+
+	doc := `{
+        "asyncapi": "2.6.0",
+        "defaultContentType": "application/json",
+        "servers": {
+            "production": {
+                "url": "broker.example.com:{port}",
+                "protocol": "kafka",
+                "tags": [{"name": "env:production"}],
+                "variables": {"port": {"$ref": "#/components/serverVariables/port"}},
+                "bindings": {"$ref": "#/components/serverBindings/kafka"}
+            }
+        },
+        "channels": {
+            "lightMeasured": {"$ref": "#/components/channels/lightMeasured"}
+        },
+        "components": {
+            "serverVariables": {
+                "port": {"default": "9092", "enum": ["9092", "9093"]}
+            },
+            "serverBindings": {
+                "kafka": {"kafka": {"schemaRegistryUrl": "https://registry.example.com"}}
+            },
+            "channelBindings": {
+                "kafka": {"kafka": {"topic": "light-measured", "partitions": 3}}
+            },
+            "messageBindings": {
+                "kafka": {"kafka": {"key": {"type": "string"}}}
+            },
+            "channels": {
+                "lightMeasured": {
+                    "description": "Measured light",
+                    "servers": ["production"],
+                    "bindings": {"$ref": "#/components/channelBindings/kafka"},
+                    "publish": {
+                        "operationId": "receiveLightMeasurement",
+                        "security": [{"apiKey": []}],
+                        "message": {
+                            "messageId": "lightMeasured",
+                            "bindings": {"$ref": "#/components/messageBindings/kafka"},
+                            "payload": {"type": "object"}
+                        }
+                    }
+                }
+            }
+        }
+    }`
+	var spec map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(doc), &spec), "test spec should be valid JSON")
+
+	assert.NoError(t, importAPISpec(spec), "import should not return error")
+	exported, err := exportAPISpec(root)
+	assert.NoError(t, err, "export should not return error")
+	assertSameJSON(t, spec, exported, "exported spec does not match")
+}
+
+func TestAsyncAPI3RoundTrip(t *testing.T) {
+	startFakeTCMD(t)
+	root = "asyncapi3-test"
+
+	doc := `{
+        "asyncapi": "3.0.0",
+        "info": {
+            "title": "Streetlights API",
+            "version": "1.0.0",
+            "tags": [{"$ref": "#/components/tags/lights"}],
+            "externalDocs": {"url": "https://example.com/docs"}
+        },
+        "servers": {
+            "production": {
+                "host": "broker.example.com:{port}",
+                "pathname": "/mqtt",
+                "protocol": "mqtt",
+                "variables": {"port": {"default": "1883"}},
+                "security": [{"$ref": "#/components/securitySchemes/apiKey"}, {"type": "userPassword"}]
+            }
+        },
+        "channels": {
+            "lightMeasured": {
+                "address": "smartylighting/{streetlightId}/measured",
+                "title": "Light measured",
+                "servers": [{"$ref": "#/servers/production"}],
+                "parameters": {
+                    "streetlightId": {"description": "Street light ID", "enum": ["a", "b"], "default": "a", "location": "$message.payload#/id"}
+                },
+                "messages": {
+                    "lightMeasured": {"$ref": "#/components/messages/lightMeasured"},
+                    "lightOff": {"name": "lightOff", "payload": {"type": "object"}}
+                }
+            },
+            "lightAck": {"$ref": "#/components/channels/lightAck"}
+        },
+        "operations": {
+            "receiveLightMeasurement": {
+                "action": "receive",
+                "summary": "Receive measured light",
+                "channel": {"$ref": "#/channels/lightMeasured"},
+                "messages": [
+                    {"$ref": "#/channels/lightMeasured/messages/lightMeasured"},
+                    {"$ref": "#/channels/lightMeasured/messages/lightOff"}
+                ],
+                "reply": {
+                    "address": {"location": "$message.header#/replyTo"},
+                    "channel": {"$ref": "#/channels/lightAck"},
+                    "messages": [{"$ref": "#/components/channels/lightAck/messages/ack"}]
+                }
+            },
+            "sendAck": {"$ref": "#/components/operations/sendAck"}
+        },
+        "components": {
+            "tags": {"lights": {"name": "lights", "description": "Light operations"}},
+            "securitySchemes": {"apiKey": {"type": "apiKey", "in": "user"}},
+            "messages": {"lightMeasured": {"payload": {"type": "integer"}}},
+            "channels": {
+                "lightAck": {
+                    "address": null,
+                    "messages": {"ack": {"payload": {"type": "string"}}}
+                }
+            },
+            "operations": {
+                "sendAck": {
+                    "action": "send",
+                    "channel": {"$ref": "#/channels/lightAck"},
+                    "reply": {"$ref": "#/components/replies/ack"}
+                }
+            },
+            "replies": {"ack": {"address": {"$ref": "#/components/replyAddresses/replyTo"}}},
+            "replyAddresses": {"replyTo": {"description": "Reply queue", "location": "$message.header#/replyTo"}}
+        }
+    }`
+	var spec map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(doc), &spec), "test spec should be valid JSON")
+
+	assert.NoError(t, importAPISpec(spec), "import should not return error")
+	assert.Greater(t, getAssetDataType(root+"#/channels/lightMeasured/messages/lightOff"), 0, "channel message should be typed by its JSON pointer scoped by the spec root")
+
+	exported, err := exportAPISpec(root)
+	assert.NoError(t, err, "export should not return error")
+	// channel address null is dropped
+	delete(spec["components"].(map[string]interface{})["channels"].(map[string]interface{})["lightAck"].(map[string]interface{}), "address")
+	assertSameJSON(t, spec, exported, "exported spec does not match")
+}
+
+func TestAsyncAPI3DataTypeScope(t *testing.T) {
+	startFakeTCMD(t)
+	doc := `{
+        "asyncapi": "3.0.0",
+        "channels": {"lightMeasured": {"address": "%s"}},
+        "operations": {
+            "onLightMeasured": {"action": "receive", "channel": {"$ref": "#/channels/lightMeasured"}},
+            "onLight": {"action": "receive", "channel": {"$ref": "#/components/channels/light~1measured"},
+                "messages": [{"$ref": "#/components/channels/light~1measured/messages/light~0on"}]}
+        },
+        "components": {"channels": {"light/measured": {"messages": {"light~on": {"payload": {"type": "string"}}}}}}
+    }`
+	specs := make(map[string]map[string]interface{})
+	for _, name := range []string{"spec-a", "spec-b"} {
+		root = name
+		var spec map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(fmt.Sprintf(doc, name)), &spec), "test spec should be valid JSON")
+		assert.NoError(t, importAPISpec(spec), "import should not return error")
+		specs[name] = spec
+	}
+	a, b := getAssetDataType("spec-a#/channels/lightMeasured"), getAssetDataType("spec-b#/channels/lightMeasured")
+	assert.Greater(t, a, 0, "channel should be typed by its JSON pointer scoped by the spec root")
+	assert.Greater(t, b, 0, "channel should be typed by its JSON pointer scoped by the spec root")
+	assert.NotEqual(t, a, b, "specs should not share data types of the same JSON pointer")
+
+	message := "#/components/channels/light~1measured/messages/light~0on"
+	assert.Greater(t, getAssetDataType(message), 0, "component channel message should be typed by its escaped JSON pointer")
+	exported, err := exportAPISpec("spec-b")
+	assert.NoError(t, err, "export should not return error")
+	assertSameJSON(t, specs["spec-b"], exported, "exported spec should keep local and component refs")
+
+	root = "spec-a"
+	assert.NoError(t, cleanAsyncAPISpec(specs["spec-a"]), "clean should not return error")
+	assert.Equal(t, 0, getAssetDataType("spec-a#/channels/lightMeasured"), "data type of the cleaned spec should be deleted")
+	assert.Greater(t, getAssetDataType(message), 0, "component data type used by another spec should be kept")
+	assert.Equal(t, b, getAssetDataType("spec-b#/channels/lightMeasured"), "data type of another spec should be kept")
+	exported, err = exportAPISpec("spec-b")
+	assert.NoError(t, err, "export should not return error")
+	assertSameJSON(t, specs["spec-b"], exported, "other spec should not be changed by clean")
+
+	root = "spec-b"
+	assert.NoError(t, cleanAsyncAPISpec(specs["spec-b"]), "clean should not return error")
+	assert.Equal(t, 0, getAssetDataType(message), "data type of an escaped JSON pointer should be deleted with its last spec")
+}
+
+func TestExpandComponents(t *testing.T) {
+	doc := `{
+        "asyncapi": "2.0.0",
+        "channels": {
+            "light/measured": {
+                "publish": {"message": {"$ref": "#/components/messages/lightMeasured"}}
+            }
+        },
+        "components": {
+            "messages": {
+                "lightMeasured": {"payload": {"$ref": "#/components/schemas/light"}}
+            },
+            "schemas": {
+                "light": {"type": "object", "properties": {"zone": {"$ref": "#/components/schemas/zone"}, "unit": {"$ref": "common.json#/Unit"}}},
+                "lumens": {"$ref": "#/components/schemas/light"},
+                "zone": {"type": "object", "properties": {"subZones": {"type": "array", "items": {"$ref": "#/components/schemas/zone"}}}}
+            }
+        }
+    }`
+	var spec map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(doc), &spec), "test spec should be valid JSON")
+	assert.NoError(t, expandComponents(spec), "components should be expanded")
+
+	zone := `{"type": "object", "properties": {"subZones": {"type": "array", "items": {"$ref": "#/components/schemas/zone"}}}}`
+	light := `{"type": "object", "properties": {"zone": ` + zone + `, "unit": {"$ref": "common.json#/Unit"}}}`
+	var expected interface{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"payload": `+light+`}`), &expected), "expected message should be valid JSON")
+	assertSameJSON(t, expected, getRef(spec, "#/channels/light~1measured/publish/message"), "message should be inlined with nested refs")
+	assert.NoError(t, json.Unmarshal([]byte(light), &expected), "expected schema should be valid JSON")
+	assertSameJSON(t, expected, getRef(spec, "#/components/schemas/lumens"), "ref to a ref should be inlined")
+	assert.NoError(t, json.Unmarshal([]byte(zone), &expected), "expected schema should be valid JSON")
+	assertSameJSON(t, expected, getRef(spec, "#/components/schemas/light/properties/zone"), "recursive ref should be kept in inlined component")
+	_, err := json.Marshal(spec)
+	assert.NoError(t, err, "expanded spec should not contain cycles")
+
+	spec["channels"] = map[string]interface{}{"a": map[string]interface{}{"$ref": "#/components/channels/missing"}}
+	assert.Error(t, expandComponents(spec), "missing component should be rejected")
+}
+
+// compare JSON of two values, so they match regardless of Go types of arrays and maps
+func assertSameJSON(t *testing.T, expected, actual interface{}, msg string) {
+	e, err := json.Marshal(expected)
+	assert.NoError(t, err, "expected value should be serializable")
+	a, err := json.Marshal(actual)
+	assert.NoError(t, err, "actual value should be serializable")
+	assert.JSONEq(t, string(e), string(a), msg)
+}
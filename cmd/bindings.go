@@ -0,0 +1,227 @@
+package cmd
+
+/*
+Copyright © 2020 Yueming Xu <yxu@tibco.com>
+This file is subject to the license terms contained in the license file that is distributed with this file.
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// binding fields that are schema objects, keyed by protocol.
+// other object fields, e.g., AMQP exchange and queue, are stored as nested assets of their fields.
+var bindingSchemaFields = map[string]map[string]bool{
+	"kafka": {"groupId": true, "clientId": true, "key": true},
+	"http":  {"query": true, "headers": true},
+	"ws":    {"query": true, "headers": true},
+}
+
+// returns name of the asset data type of a protocol binding, e.g., kafkaMessageBinding
+func bindingDataType(protocol, level string) string {
+	return fmt.Sprintf("%s%sBinding", protocol, strings.Title(level))
+}
+
+// create bindings of a channel, operation, message or server, as specified by level.
+// each protocol binding is typed by its protocol and level, and its fields are created as child assets.
+func createBindingsAsset(bindings interface{}, level string, parent int) error {
+	tid, err := refDataType(bindings)
+	if err != nil {
+		return err
+	}
+	return createComponentBindingsAsset("bindings", bindings, level, tid, parent)
+}
+
+// create a bindings object of a specified name, which is also used for reusable bindings in components,
+// e.g., #/components/messageBindings/<name>
+func createComponentBindingsAsset(name string, bindings interface{}, level string, tid int, parent int) error {
+	bm, ok := bindings.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("bindings type %T is not a map", bindings)
+	}
+	asset := Asset{
+		Name:                    name,
+		Label:                   name,
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	if tid > 0 {
+		asset.AssetDataType = strconv.Itoa(tid)
+	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+
+	var errs specErrors
+	for protocol, b := range bm {
+		if protocol == "$ref" {
+			continue
+		}
+		errs.add(createBindingAsset(protocol, b, level, pid), protocol)
+	}
+	return errs.result()
+}
+
+func createBindingAsset(protocol string, binding interface{}, level string, parent int) error {
+	fields, ok := binding.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("%s binding type %T is not a map", protocol, binding)
+	}
+	tid, err := setRef(bindingDataType(protocol, level))
+	if err != nil {
+		return err
+	}
+	asset := Asset{
+		Name:                    protocol,
+		Label:                   protocol,
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		AssetDataType:           strconv.Itoa(tid),
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+	return createBindingFieldAssets(protocol, fields, pid)
+}
+
+// create child assets for fields of a binding, or fields of a nested object in a binding
+func createBindingFieldAssets(protocol string, fields map[string]interface{}, parent int) error {
+	var errs specErrors
+	for k, v := range fields {
+		var err error
+		if value, ok := v.(map[string]interface{}); ok {
+			if bindingSchemaFields[protocol][k] {
+				var tid int
+				if tid, err = refDataType(value); err == nil {
+					err = createSchemaAsset(k, value, tid, parent, false)
+				}
+			} else {
+				err = createBindingObjectAsset(protocol, k, value, parent)
+			}
+		} else {
+			err = createBindingValueAsset(k, v, parent)
+		}
+		errs.add(err, k)
+	}
+	return errs.result()
+}
+
+func createBindingObjectAsset(protocol, name string, fields map[string]interface{}, parent int) error {
+	asset := Asset{
+		Name:                    name,
+		Label:                   name,
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+	return createBindingFieldAssets(protocol, fields, pid)
+}
+
+// create a property asset for a binding field value, typed as string, boolean, integer or array if applicable
+func createBindingValueAsset(name string, value interface{}, parent int) error {
+	asset := Asset{
+		Name:                    name,
+		Label:                   name,
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Property"],
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	dataType := ""
+	if s, ok := value.(string); ok {
+		asset.Comment = s
+		dataType = "string"
+	} else {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to serialize binding field %s", name)
+		}
+		asset.Comment = string(data)
+		switch v := value.(type) {
+		case bool:
+			dataType = "boolean"
+		case float64:
+			if v == math.Trunc(v) {
+				dataType = "integer"
+			}
+		case []interface{}:
+			dataType = "array"
+		}
+	}
+	if tid := dataTypeID(dataType); tid > 0 {
+		asset.AssetDataType = strconv.Itoa(tid)
+	}
+	_, err := createAsset(asset)
+	return err
+}
+
+func extractBindingsAsset(child *Asset, parent map[string]interface{}) error {
+	bindings := make(map[string]interface{})
+	if ok := setComponentRef(child, bindings); ok {
+		parent[child.Label] = bindings
+		return nil
+	}
+	return extractComponentBindingsAsset(child, parent)
+}
+
+// extract a bindings object, or reusable bindings in components
+func extractComponentBindingsAsset(child *Asset, parent map[string]interface{}) error {
+	children, err := getChildrenAsset(child.ID)
+	if err != nil {
+		return errors.Wrap(err, "Failed to fetch bindings")
+	}
+	if len(children) == 0 && len(child.Comment) > 0 {
+		// bindings imported by earlier versions are stored as JSON
+		return extractSimpleAsset(child, parent)
+	}
+
+	bindings := make(map[string]interface{})
+	parent[child.Label] = bindings
+	for _, c := range children {
+		binding := make(map[string]interface{})
+		bindings[c.Label] = binding
+		if err := extractBindingFieldAssets(c.Label, &c, binding); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractBindingFieldAssets(protocol string, child *Asset, fields map[string]interface{}) error {
+	children, err := getChildrenAsset(child.ID)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to fetch fields of %s binding", protocol)
+	}
+	for _, c := range children {
+		switch {
+		case c.AssetType == AssetTypes["JSON Property"]:
+			extractSimpleAsset(&c, fields)
+		case bindingSchemaFields[protocol][c.Label]:
+			extractSchemaAsset(&c, fields, false)
+		default:
+			object := make(map[string]interface{})
+			fields[c.Label] = object
+			if err := extractBindingFieldAssets(protocol, &c, object); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package cmd
+
+/*
+Copyright © 2020 Yueming Xu <yxu@tibco.com>
+This file is subject to the license terms contained in the license file that is distributed with this file.
+*/
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// rootName derives a root asset name from a spec file name by removing its extension, e.g., v1.2-events.yml -> v1.2-events
+func rootName(file string) string {
+	fn := filepath.Base(file)
+	return strings.TrimSuffix(fn, filepath.Ext(fn))
+}
+
+// extensions of spec files that are imported in bundle mode
+var specFileExtensions = map[string]bool{".json": true, ".yaml": true, ".yml": true}
+
+// findSpecFiles returns spec files in a directory and its sub-directories, or files matching a glob pattern
+func findSpecFiles(pattern string) ([]string, error) {
+	var files []string
+	if info, err := os.Stat(pattern); err == nil && info.IsDir() {
+		err := filepath.Walk(pattern, func(path string, fi os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !fi.IsDir() && specFileExtensions[strings.ToLower(filepath.Ext(path))] {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to list spec files in %s", pattern)
+		}
+	} else {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid glob pattern %s", pattern)
+		}
+		for _, m := range matches {
+			if fi, err := os.Stat(m); err == nil && !fi.IsDir() {
+				files = append(files, m)
+			}
+		}
+	}
+	if len(files) == 0 {
+		return nil, errors.Errorf("No spec file is found in %s", pattern)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// isAPISpecFile returns true if a file is an AsyncAPI or OpenAPI document, so shared schema files are skipped
+func isAPISpecFile(file string) bool {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return false
+	}
+	var doc map[string]interface{}
+	if err := decode(data, &doc); err != nil {
+		return false
+	}
+	return doc["asyncapi"] != nil || doc["openapi"] != nil
+}
+
+// bundleRootName returns the root name of a spec file specified by its path relative to the bundle directory
+// or its file name, or derives it from the file name if it is not specified
+func bundleRootName(file, pattern string, roots map[string]string) string {
+	if rel, err := filepath.Rel(pattern, file); err == nil {
+		if name, ok := roots[filepath.ToSlash(rel)]; ok {
+			return name
+		}
+	}
+	for _, k := range []string{filepath.ToSlash(file), filepath.Base(file)} {
+		if name, ok := roots[k]; ok {
+			return name
+		}
+	}
+	return rootName(file)
+}
+
+// bundleResult is the import result of a spec file in bundle mode
+type bundleResult struct {
+	file  string
+	root  string
+	stats importStats
+	err   error
+}
+
+// importBundle imports each AsyncAPI and OpenAPI spec found in a directory or glob pattern as a separate transaction.
+// Data types are cached across specs, so specs that define the same components share their data types.
+func importBundle(pattern string, roots map[string]string) ([]bundleResult, error) {
+	files, err := findSpecFiles(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []bundleResult
+	used := make(map[string]string)
+	for _, f := range files {
+		if !isAPISpecFile(f) {
+			fmt.Printf("skip %s, it is not an asyncapi or openapi spec\n", f)
+			continue
+		}
+		result := bundleResult{file: f, root: bundleRootName(f, pattern, roots)}
+		if other, ok := used[result.root]; ok {
+			result.err = errors.Errorf("root name %s is already used by %s", result.root, other)
+			results = append(results, result)
+			continue
+		}
+		used[result.root] = f
+
+		fmt.Println("import", f)
+		root = result.root
+		stats = importStats{}
+		spec, err := readSpec(f)
+		if err == nil {
+			err = importWithRollback(spec)
+			printImportSummary(err)
+		}
+		result.stats = stats
+		result.err = err
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// print import result of each spec file, and return the number of failed files
+func printBundleSummary(results []bundleResult) int {
+	failed := 0
+	for _, r := range results {
+		if r.err != nil {
+			failed++
+		}
+	}
+	fmt.Printf("bundle summary: %d succeeded, %d failed\n", len(results)-failed, failed)
+	for _, r := range results {
+		if r.err != nil {
+			fmt.Printf("  failed %s -> %s: %v\n", r.file, r.root, r.err)
+			continue
+		}
+		fmt.Printf("  ok %s -> %s, created %d, updated %d, unchanged %d\n",
+			r.file, r.root, r.stats.created, r.stats.updated, r.stats.unchanged)
+	}
+	return failed
+}
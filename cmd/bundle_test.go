@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRootName(t *testing.T) {
+	assert.Equal(t, "v1.2-events", rootName("apis/v1.2-events.yml"), "only the extension should be removed")
+	assert.Equal(t, "streetlights", rootName("apis/streetlights"), "file name without extension should be used")
+	assert.Equal(t, "petstore", rootName("petstore.yaml"), "extension should be removed")
+}
+
+func TestImportBundle(t *testing.T) {
+	fake := startFakeTCMD(t)
+	dir, err := ioutil.TempDir("", "bundle")
+	assert.NoError(t, err, "temp dir should be created")
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	files := map[string]string{
+		"a.yml":              "asyncapi: 2.0.0\ninfo:\n  title: A\n  version: 1.0.0\ncomponents:\n  schemas:\n    Light:\n      type: integer\n",
+		"bad.yml":            "asyncapi: 2.0.0\ninfo: invalid\ncomponents:\n  schemas:\n    Zone:\n      type: string\n",
+		"schemas/light.yaml": "Light:\n  type: integer\n",
+		"sub/b.json":         `{"asyncapi": "2.0.0", "components": {"schemas": {"Light": {"type": "integer"}, "Zone": {"type": "string"}}}}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755), "test dir should be created")
+		assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644), "test file should be written")
+	}
+
+	results, err := importBundle(dir, map[string]string{"sub/b.json": "bee"})
+	assert.NoError(t, err, "bundle should be imported")
+	if assert.Len(t, results, 3, "only API specs should be imported") {
+		assert.Equal(t, "a", results[0].root, "root name should be derived from file name")
+		assert.NoError(t, results[0].err, "valid spec should be imported")
+		assert.Equal(t, "bad", results[1].root, "root name should be derived from file name")
+		assert.Error(t, results[1].err, "invalid spec should fail")
+		assert.Equal(t, "bee", results[2].root, "root name should be explicit")
+		assert.NoError(t, results[2].err, "spec after a failed spec should be imported")
+	}
+	assert.Equal(t, 1, printBundleSummary(results), "summary should count failed files")
+
+	names := make(map[string]int)
+	for _, dt := range fake.dataTypes {
+		names[dt.Name]++
+	}
+	assert.Equal(t, 1, names["#/components/schemas/Light"], "specs should share component data types")
+	assert.Equal(t, 1, names["#/components/schemas/Zone"], "data type rolled back by a failed spec should be created again")
+	bad, err := getRootAsset("bad")
+	assert.NoError(t, err, "root asset should be queried")
+	assert.Nil(t, bad, "failed spec should be rolled back")
+}
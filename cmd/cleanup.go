@@ -9,14 +9,9 @@ Test command: ./tcmdtool clean -i test-data/slack_events_api.json
 
 import (
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/pkg/errors"
-
 	"github.com/spf13/cobra"
 )
 
@@ -24,22 +19,18 @@ import (
 var cleanCmd = &cobra.Command{
 	Use:   "clean",
 	Short: "Cleanup an API spec in TCMD",
-	Long:  `Cleanup an API spec in TCMD`,
+	Long: `Cleanup an API spec in TCMD.
+Definitions of external documents referred by the spec may be shared by other specs, so they are kept,
+unless --external is specified. Component data types still used by other specs are kept as well.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("clean", input)
-		data, err := ioutil.ReadFile(input)
+		spec, err := readSpec(input)
 		if err != nil {
 			panic(err)
 		}
 		// set root asset name as input file name if it is not specified
 		if root == "" {
-			fn := filepath.Base(input)
-			root = fn[0:strings.Index(fn, ".")]
-		}
-
-		var spec map[string]interface{}
-		if err = decode(data, &spec); err != nil {
-			panic(err)
+			root = rootName(input)
 		}
 		if spec["asyncapi"] != nil {
 			fmt.Printf("Read asyncapi spec version %s\n", spec["asyncapi"])
@@ -53,54 +44,138 @@ var cleanCmd = &cobra.Command{
 				panic(err)
 			}
 		}
+		if external {
+			if err := cleanExternalDefinitions(); err != nil {
+				panic(err)
+			}
+		}
 	},
 }
 
+// external is set to also delete definitions of external documents
+var external bool
+
 func init() {
 	rootCmd.AddCommand(cleanCmd)
 
 	cleanCmd.Flags().StringVarP(&input, "input", "i", "", "name of the file to be cleaned")
 	cleanCmd.Flags().StringVarP(&root, "root", "r", "", "name of root asset created from input file")
+	cleanCmd.Flags().BoolVar(&external, "external", false, "also delete root assets and data types of external documents referred by the spec")
 	cleanCmd.MarkFlagRequired("input")
 }
 
-func delete(path string) ([]byte, error) {
-	reqURL := fmt.Sprintf("%s/%s", url, path)
-	reqAuth := fmt.Sprintf("Basic %s", authtoken)
-
-	req, err := http.NewRequest(http.MethodDelete, reqURL, nil)
+// delete root asset of the specified name and all its descendants
+func cleanRootAsset(name string) error {
+	asset, err := getRootAsset(name)
 	if err != nil {
-		return nil, errors.Wrapf(err, "Failed to create DELETE request %s", reqURL)
+		return errors.Wrapf(err, "Failed to find root asset %s", name)
 	}
-	fmt.Printf("DELETE %s using token %s\n", req.URL, reqAuth)
+	if asset == nil {
+		return nil
+	}
+	fmt.Printf("cleanup asset %d -> %s\n", asset.ID, name)
+	return deleteAssetTree(asset.ID)
+}
 
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", reqAuth)
+// delete descendants of an asset before deleting the asset itself
+func deleteAssetTree(id int) error {
+	children, err := getChildrenAsset(id)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to fetch children of asset %d", id)
+	}
+	for _, c := range children {
+		if err := deleteAssetTree(c.ID); err != nil {
+			return err
+		}
+	}
+	if err := deleteAsset(id); err != nil {
+		return errors.Wrapf(err, "Failed to delete asset %d", id)
+	}
+	return nil
+}
 
-	client := &http.Client{Timeout: time.Duration(5) * time.Second}
-	resp, err := client.Do(req)
+// returns true if a data type of a component is still used by any asset.
+// Specs that define the same component share its data type, e.g., in bundle mode, so it is deleted only after
+// assets of all specs are deleted. A data type scoped by a root asset is used by its own spec only.
+func sharedDataTypeInUse(tid int, name string) (bool, error) {
+	if !strings.HasPrefix(name, "#/") {
+		return false, nil
+	}
+	used, err := getAssetsByDataType(tid)
 	if err != nil {
-		return nil, errors.Wrapf(err, "Failed http DELETE %s", req.URL)
+		return false, errors.Wrapf(err, "Failed to fetch assets of data type %s", name)
+	}
+	if len(used) > 0 {
+		fmt.Printf("keep data type %d -> %s used by %d assets\n", tid, name, len(used))
+		return true, nil
+	}
+	return false, nil
+}
+
+// delete asset data types registered for #/components/<category>/<name> of a spec, unless other specs still use them
+func cleanComponentDataTypes(spec interface{}) error {
+	components := getRef(spec, "#/components")
+	if components == nil {
+		return nil
+	}
+	cm, ok := components.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("components type %T is not a map", components)
+	}
+	for cat, val := range cm {
+		om, ok := val.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for k := range om {
+			name := jsonPointer("components", cat, k)
+			if tid := getAssetDataType(name); tid > 0 {
+				inUse, err := sharedDataTypeInUse(tid, name)
+				if err != nil {
+					return err
+				}
+				if inUse {
+					continue
+				}
+				// remove asset data types
+				fmt.Printf("cleanup data type %d -> %s\n", tid, k)
+				if err := deleteAssetDataType(tid); err != nil {
+					return errors.Wrapf(err, "Failed to delete data type %d", tid)
+				}
+			}
+		}
 	}
-	fmt.Printf("TCMD DELETE status: %d\n", resp.StatusCode)
+	return nil
+}
 
-	defer resp.Body.Close()
-	if resp.StatusCode >= 300 {
-		return nil, errors.Errorf("HTTP DELETE returned status %d", resp.StatusCode)
+// delete root assets of external documents referred by a spec, and then data types of their definitions
+func cleanExternalDefinitions() error {
+	docs := make(map[string]interface{})
+	for ref := range externalDefinitions {
+		docs[ref[:strings.Index(ref, "#")]] = true
+	}
+	for _, doc := range sortedKeys(docs) {
+		if err := cleanRootAsset(doc); err != nil {
+			return err
+		}
+	}
+	for _, ref := range sortedKeys(externalDefinitions) {
+		if tid := getAssetDataType(ref); tid > 0 {
+			fmt.Printf("cleanup data type %d -> %s\n", tid, ref)
+			if err := deleteAssetDataType(tid); err != nil {
+				return errors.Wrapf(err, "Failed to delete data type %d", tid)
+			}
+		}
 	}
-	return ioutil.ReadAll(resp.Body)
+	return nil
 }
 
 // delete asset data type of specified ID
 func deleteAssetDataType(tid int) error {
-	path := fmt.Sprintf("%s/%s/datatype/%d", TCDataspace, TCDataset, tid)
-	_, err := delete(path)
-	return err
+	return client.DeleteDataType(tcmdContext(), tid)
 }
 
 // delete asset of specified ID
 func deleteAsset(tid int) error {
-	path := fmt.Sprintf("asset/%d", tid)
-	_, err := delete(path)
-	return err
+	return client.DeleteAsset(tcmdContext(), tid)
 }
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCleanRootAsset(t *testing.T) {
+	fake := startFakeTCMD(t)
+	other, err := createAsset(Asset{Name: "other-api", Label: "other-api", AssetType: AssetTypes["JSON Element"]})
+	assert.NoError(t, err, "root asset should be created")
+	nested, err := createAsset(Asset{Name: "info", Label: "info", Parent: strconv.Itoa(other), AssetType: AssetTypes["JSON Element"]})
+	assert.NoError(t, err, "nested asset should be created")
+	rid, err := createAsset(Asset{Name: "info", Label: "info", AssetType: AssetTypes["JSON Element"]})
+	assert.NoError(t, err, "root asset should be created")
+
+	asset, err := getRootAsset("info")
+	assert.NoError(t, err, "root asset should be queried")
+	if assert.NotNil(t, asset, "root asset should be found") {
+		assert.Equal(t, rid, asset.ID, "nested asset of the same name should not be matched")
+	}
+
+	assert.NoError(t, cleanRootAsset("info"), "clean should not return error")
+	assert.Contains(t, fake.assets, other, "other root asset should be kept")
+	assert.Contains(t, fake.assets, nested, "nested asset of the same name should be kept")
+	assert.NotContains(t, fake.assets, rid, "root asset should be deleted")
+}
@@ -0,0 +1,610 @@
+package cmd
+
+/*
+Copyright © 2020 Yueming Xu <yxu@tibco.com>
+This file is subject to the license terms contained in the license file that is distributed with this file.
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// asyncAPIConverter converts an exported AsyncAPI spec between 2.x and 3.0, and collects warnings of
+// constructs that cannot be translated losslessly.
+type asyncAPIConverter struct {
+	spec     map[string]interface{}
+	version  string
+	warnings []string
+}
+
+// convertAsyncAPISpec returns a copy of an AsyncAPI spec converted to a specified version, and warnings of lossy translations
+func convertAsyncAPISpec(spec interface{}, version string) (map[string]interface{}, []string, error) {
+	if !strings.HasPrefix(version, "2.") && !strings.HasPrefix(version, "3.") {
+		return nil, nil, errors.Errorf("AsyncAPI version %s is not supported", version)
+	}
+	from := getString(spec, "#/asyncapi")
+	if len(from) == 0 {
+		return nil, nil, errors.New("spec is not an AsyncAPI spec")
+	}
+
+	// copy the spec, so it is not modified, and its arrays and maps are of generic JSON types
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to serialize spec")
+	}
+	c := &asyncAPIConverter{version: version}
+	if err := json.Unmarshal(data, &c.spec); err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to copy spec")
+	}
+
+	switch {
+	case strings.HasPrefix(from, "2.") && strings.HasPrefix(version, "3."):
+		c.to3()
+	case strings.HasPrefix(from, "3.") && strings.HasPrefix(version, "2."):
+		c.to2()
+	default:
+		c.spec["asyncapi"] = version
+	}
+	return c.spec, c.warnings, nil
+}
+
+func (c *asyncAPIConverter) warn(format string, args ...interface{}) {
+	c.warnings = append(c.warnings, fmt.Sprintf(format, args...))
+}
+
+// returns a node as a map, or nil if it is not a map
+func mapOf(node interface{}) map[string]interface{} {
+	m, _ := node.(map[string]interface{})
+	return m
+}
+
+// returns sorted keys of a map, so converted names are deterministic
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// returns the last token of a JSON pointer, e.g., lightMeasured of #/components/messages/lightMeasured
+func refName(ref string) string {
+	token := ref[strings.LastIndex(ref, "/")+1:]
+	return strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+}
+
+// resolve a component ref in the spec, or return the node if it is not a ref
+func (c *asyncAPIConverter) resolve(node interface{}) map[string]interface{} {
+	if ref := getString(node, "#/$ref"); strings.HasPrefix(ref, "#/components/") {
+		if m := mapOf(getRef(c.spec, ref)); m != nil {
+			return m
+		}
+		c.warn("ref %s is not defined", ref)
+	}
+	return mapOf(node)
+}
+
+// copy properties of a node except specified keys
+func copyExcept(node map[string]interface{}, exclude ...string) map[string]interface{} {
+	result := make(map[string]interface{})
+	for k, v := range node {
+		result[k] = v
+	}
+	for _, k := range exclude {
+		delete(result, k)
+	}
+	return result
+}
+
+// drop properties not supported by the target version, and warn if any of them is set
+func (c *asyncAPIConverter) drop(node map[string]interface{}, path string, keys ...string) {
+	for _, k := range keys {
+		if _, ok := node[k]; ok {
+			delete(node, k)
+			c.warn("%s/%s is dropped in AsyncAPI %s", path, k, c.version)
+		}
+	}
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// derive a unique channel ID from a 2.x channel address, e.g., light/{id}/measured -> lightIdMeasured
+func channelID(address string, used map[string]bool) string {
+	var id string
+	for _, w := range nonAlphanumeric.Split(address, -1) {
+		if len(w) == 0 {
+			continue
+		}
+		if len(id) > 0 {
+			w = strings.ToUpper(w[:1]) + w[1:]
+		}
+		id += w
+	}
+	if len(id) == 0 {
+		id = "channel"
+	}
+	name := id
+	for i := 2; used[name]; i++ {
+		name = id + strconv.Itoa(i)
+	}
+	used[name] = true
+	return name
+}
+
+// to3 moves operations out of 2.x channels, and messages of operations into channels
+func (c *asyncAPIConverter) to3() {
+	s := c.spec
+	s["asyncapi"] = c.version
+
+	// root tags and externalDocs are moved into info
+	info := mapOf(s["info"])
+	if info == nil {
+		info = make(map[string]interface{})
+		s["info"] = info
+	}
+	for _, k := range []string{"tags", "externalDocs"} {
+		if v, ok := s[k]; ok {
+			info[k] = v
+			delete(s, k)
+		}
+	}
+
+	for name, svr := range mapOf(s["servers"]) {
+		c.serverTo3(name, mapOf(svr))
+	}
+
+	if channels := mapOf(s["channels"]); channels != nil {
+		result := make(map[string]interface{})
+		operations := make(map[string]interface{})
+		used := make(map[string]bool)
+		for _, address := range sortedKeys(channels) {
+			ch := mapOf(channels[address])
+			if ref := getString(ch, "#/$ref"); len(ref) > 0 {
+				c.warn("#/channels/%s refers to %s, which is inlined", address, ref)
+				ch = c.resolve(ch)
+			}
+			id := channelID(address, used)
+			ch3 := c.channelTo3(ch, jsonPointer("channels", address))
+			ch3["address"] = address
+
+			messages := make(map[string]interface{})
+			for _, verb := range []string{"publish", "subscribe"} {
+				op := mapOf(ch[verb])
+				if op == nil {
+					continue
+				}
+				// 2.x publish means the application receives messages published by others
+				action := "send"
+				if verb == "publish" {
+					action = "receive"
+				}
+				opID := getString(op, "#/operationId")
+				if len(opID) == 0 || operations[opID] != nil {
+					opID = id + strings.Title(verb)
+				}
+				operations[opID] = c.operationTo3(op, action, id, messages, opID)
+			}
+			if len(messages) > 0 {
+				ch3["messages"] = messages
+			}
+			result[id] = ch3
+		}
+		s["channels"] = result
+		if len(operations) > 0 {
+			s["operations"] = operations
+		}
+	}
+
+	components := mapOf(s["components"])
+	for name, ch := range mapOf(components["channels"]) {
+		cm := mapOf(ch)
+		path := jsonPointer("components", "channels", name)
+		c.drop(cm, path, "publish", "subscribe")
+		mapOf(components["channels"])[name] = c.channelTo3(cm, path)
+	}
+	for name, m := range mapOf(components["messages"]) {
+		mapOf(components["messages"])[name] = c.messageTo3(mapOf(m))
+	}
+	for name, t := range mapOf(components["messageTraits"]) {
+		c.drop(mapOf(t), jsonPointer("components", "messageTraits", name), "messageId", "schemaFormat")
+	}
+	for name, t := range mapOf(components["operationTraits"]) {
+		c.drop(mapOf(t), jsonPointer("components", "operationTraits", name), "operationId")
+	}
+	for name, p := range mapOf(components["parameters"]) {
+		mapOf(components["parameters"])[name] = c.parameterTo3(mapOf(p), jsonPointer("components", "parameters", name))
+	}
+}
+
+// replace server url by host and pathname
+func (c *asyncAPIConverter) serverTo3(name string, server map[string]interface{}) {
+	if server == nil || len(getString(server, "#/$ref")) > 0 {
+		return
+	}
+	if url := getString(server, "#/url"); len(url) > 0 {
+		if i := strings.Index(url, "://"); i >= 0 {
+			if scheme := url[:i]; scheme != getString(server, "#/protocol") {
+				c.warn("#/servers/%s/url scheme %s is dropped in AsyncAPI %s", name, scheme, c.version)
+			}
+			url = url[i+3:]
+		}
+		if i := strings.Index(url, "/"); i >= 0 {
+			server["host"] = url[:i]
+			server["pathname"] = url[i:]
+		} else {
+			server["host"] = url
+		}
+		delete(server, "url")
+	}
+	if security, ok := server["security"]; ok {
+		server["security"] = c.securityTo3(security, jsonPointer("servers", name, "security"))
+	}
+}
+
+// replace security requirements by refs of security schemes
+func (c *asyncAPIConverter) securityTo3(security interface{}, path string) []interface{} {
+	var result []interface{}
+	list, _ := security.([]interface{})
+	for i, req := range list {
+		rm := mapOf(req)
+		if len(rm) > 1 {
+			c.warn("%s/%d requires all of its schemes, but AsyncAPI %s requires any of them", path, i, c.version)
+		}
+		for _, name := range sortedKeys(rm) {
+			if scopes, _ := rm[name].([]interface{}); len(scopes) > 0 {
+				c.warn("%s/%d scopes of %s are dropped in AsyncAPI %s", path, i, name, c.version)
+			}
+			result = append(result, map[string]interface{}{"$ref": jsonPointer("components", "securitySchemes", name)})
+		}
+	}
+	return result
+}
+
+// convert 2.x channel properties other than operations
+func (c *asyncAPIConverter) channelTo3(ch map[string]interface{}, path string) map[string]interface{} {
+	ch3 := copyExcept(ch, "publish", "subscribe")
+	if servers, ok := ch["servers"].([]interface{}); ok {
+		refs := make([]interface{}, len(servers))
+		for i, s := range servers {
+			refs[i] = map[string]interface{}{"$ref": jsonPointer("servers", fmt.Sprintf("%v", s))}
+		}
+		ch3["servers"] = refs
+	}
+	if params := mapOf(ch["parameters"]); params != nil {
+		result := make(map[string]interface{})
+		for name, p := range params {
+			result[name] = c.parameterTo3(mapOf(p), path+"/parameters/"+name)
+		}
+		ch3["parameters"] = result
+	}
+	return ch3
+}
+
+// replace parameter schema by its enum, default and examples
+func (c *asyncAPIConverter) parameterTo3(param map[string]interface{}, path string) map[string]interface{} {
+	if param == nil || len(getString(param, "#/$ref")) > 0 {
+		return param
+	}
+	result := copyExcept(param, "schema")
+	schema := mapOf(param["schema"])
+	for _, k := range sortedKeys(schema) {
+		switch k {
+		case "enum", "examples":
+			list, _ := schema[k].([]interface{})
+			values := make([]interface{}, len(list))
+			for i, v := range list {
+				values[i] = fmt.Sprintf("%v", v)
+			}
+			result[k] = values
+		case "default":
+			result[k] = fmt.Sprintf("%v", schema[k])
+		case "type":
+			if schema[k] != "string" {
+				c.warn("%s/schema/type %v is dropped in AsyncAPI %s", path, schema[k], c.version)
+			}
+		default:
+			c.warn("%s/schema/%s is dropped in AsyncAPI %s", path, k, c.version)
+		}
+	}
+	return result
+}
+
+// convert a 2.x operation, and add its messages to the messages of its channel
+func (c *asyncAPIConverter) operationTo3(op map[string]interface{}, action, channelID string, messages map[string]interface{}, opID string) map[string]interface{} {
+	path := jsonPointer("operations", opID)
+	op3 := copyExcept(op, "operationId", "message")
+	op3["action"] = action
+	op3["channel"] = map[string]interface{}{"$ref": jsonPointer("channels", channelID)}
+	if security, ok := op["security"]; ok {
+		op3["security"] = c.securityTo3(security, path+"/security")
+	}
+	if traits, ok := op["traits"].([]interface{}); ok {
+		for i, t := range traits {
+			c.drop(mapOf(t), fmt.Sprintf("%s/traits/%d", path, i), "operationId")
+		}
+	}
+
+	msg, ok := op["message"]
+	if !ok {
+		return op3
+	}
+	list := []interface{}{msg}
+	if oneOf, ok := getRef(msg, "#/oneOf").([]interface{}); ok {
+		list = oneOf
+	}
+	refs := make([]interface{}, 0, len(list))
+	for i, m := range list {
+		mm := mapOf(m)
+		name := opID + "Message"
+		if ref := getString(mm, "#/$ref"); len(ref) > 0 {
+			name = refName(ref)
+		} else if id := getString(mm, "#/messageId"); len(id) > 0 {
+			name = id
+		} else if n := getString(mm, "#/name"); len(n) > 0 {
+			name = n
+		} else if len(list) > 1 {
+			name += strconv.Itoa(i)
+		}
+		messages[name] = c.messageTo3(mm)
+		refs = append(refs, map[string]interface{}{"$ref": jsonPointer("channels", channelID, "messages", name)})
+	}
+	op3["messages"] = refs
+	return op3
+}
+
+// drop messageId, and move schemaFormat into a multi format schema of payload
+func (c *asyncAPIConverter) messageTo3(message map[string]interface{}) map[string]interface{} {
+	if message == nil || len(getString(message, "#/$ref")) > 0 {
+		return message
+	}
+	result := copyExcept(message, "messageId", "schemaFormat")
+	if format := getString(message, "#/schemaFormat"); len(format) > 0 && !strings.HasPrefix(format, "application/vnd.aai.asyncapi") {
+		if payload, ok := message["payload"]; ok {
+			result["payload"] = map[string]interface{}{"schemaFormat": format, "schema": payload}
+		}
+	}
+	if traits, ok := message["traits"].([]interface{}); ok {
+		for _, t := range traits {
+			if tm := mapOf(t); tm != nil {
+				delete(tm, "messageId")
+			}
+		}
+	}
+	return result
+}
+
+// to2 moves operations into channels keyed by their addresses, and inlines messages referred by operations
+func (c *asyncAPIConverter) to2() {
+	s := c.spec
+	s["asyncapi"] = c.version
+
+	info := mapOf(s["info"])
+	for _, k := range []string{"tags", "externalDocs"} {
+		if v, ok := info[k]; ok {
+			s[k] = v
+			delete(info, k)
+		}
+	}
+	if tags, ok := s["tags"]; ok {
+		s["tags"] = c.resolveTags(tags)
+	}
+
+	for name, svr := range mapOf(s["servers"]) {
+		c.serverTo2(name, mapOf(svr))
+	}
+
+	channels := mapOf(s["channels"])
+	result := make(map[string]interface{})
+	addresses := make(map[string]string)
+	for _, id := range sortedKeys(channels) {
+		ch := c.resolve(channels[id])
+		address := getString(ch, "#/address")
+		if len(address) == 0 {
+			address = id
+			c.warn("#/channels/%s has no address, so its ID is used as address", id)
+		}
+		addresses[id] = address
+		result[address] = c.channelTo2(ch, jsonPointer("channels", id))
+	}
+
+	used := make(map[string]bool)
+	operations := mapOf(s["operations"])
+	for _, opID := range sortedKeys(operations) {
+		path := jsonPointer("operations", opID)
+		op := c.resolve(operations[opID])
+		chID := refName(getString(op, "#/channel/$ref"))
+		ch2 := mapOf(result[addresses[chID]])
+		if ch2 == nil {
+			c.warn("%s is dropped, because its channel is not found", path)
+			continue
+		}
+		verb := "subscribe"
+		if getString(op, "#/action") == "receive" {
+			verb = "publish"
+		}
+		if _, ok := ch2[verb]; ok {
+			c.warn("%s is dropped, because channel %s already has a %s operation", path, chID, verb)
+			continue
+		}
+		ch2[verb] = c.operationTo2(op, opID, c.resolve(channels[chID]), chID, used)
+	}
+	delete(s, "operations")
+
+	for _, id := range sortedKeys(channels) {
+		for name := range mapOf(c.resolve(channels[id])["messages"]) {
+			if !used[jsonPointer("channels", id, "messages", name)] {
+				c.warn("#/channels/%s/messages/%s is dropped, because no operation refers to it", id, name)
+			}
+		}
+	}
+	s["channels"] = result
+
+	components := mapOf(s["components"])
+	for name, ch := range mapOf(components["channels"]) {
+		path := jsonPointer("components", "channels", name)
+		mapOf(components["channels"])[name] = c.channelTo2(mapOf(ch), path)
+	}
+	for name, m := range mapOf(components["messages"]) {
+		mapOf(components["messages"])[name] = c.messageTo2(mapOf(m))
+	}
+	for name, p := range mapOf(components["parameters"]) {
+		mapOf(components["parameters"])[name] = c.parameterTo2(mapOf(p))
+	}
+	c.drop(components, "#/components", "operations", "replies", "replyAddresses", "tags", "externalDocs")
+}
+
+// replace server host and pathname by url
+func (c *asyncAPIConverter) serverTo2(name string, server map[string]interface{}) {
+	if server == nil || len(getString(server, "#/$ref")) > 0 {
+		return
+	}
+	path := jsonPointer("servers", name)
+	if host := getString(server, "#/host"); len(host) > 0 {
+		server["url"] = host + getString(server, "#/pathname")
+		delete(server, "host")
+		delete(server, "pathname")
+	}
+	if security, ok := server["security"]; ok {
+		server["security"] = c.securityTo2(security, path+"/security")
+	}
+	if tags, ok := server["tags"]; ok {
+		server["tags"] = c.resolveTags(tags)
+	}
+	c.drop(server, path, "title", "summary", "externalDocs")
+}
+
+// replace refs of security schemes by security requirements without scopes
+func (c *asyncAPIConverter) securityTo2(security interface{}, path string) []interface{} {
+	var result []interface{}
+	list, _ := security.([]interface{})
+	for i, s := range list {
+		ref := getString(s, "#/$ref")
+		if !strings.HasPrefix(ref, "#/components/securitySchemes/") {
+			c.warn("%s/%d is dropped, because it is not a ref of security scheme", path, i)
+			continue
+		}
+		result = append(result, map[string]interface{}{refName(ref): []interface{}{}})
+	}
+	return result
+}
+
+// inline refs of tags defined in components
+func (c *asyncAPIConverter) resolveTags(tags interface{}) []interface{} {
+	list, _ := tags.([]interface{})
+	result := make([]interface{}, len(list))
+	for i, t := range list {
+		result[i] = c.resolve(t)
+	}
+	return result
+}
+
+// convert 3.0 channel properties other than address and messages
+func (c *asyncAPIConverter) channelTo2(ch map[string]interface{}, path string) map[string]interface{} {
+	ch2 := copyExcept(ch, "address", "messages")
+	if servers, ok := ch["servers"].([]interface{}); ok {
+		names := make([]interface{}, len(servers))
+		for i, s := range servers {
+			names[i] = refName(getString(s, "#/$ref"))
+		}
+		ch2["servers"] = names
+	}
+	if params := mapOf(ch["parameters"]); params != nil {
+		result := make(map[string]interface{})
+		for name, p := range params {
+			result[name] = c.parameterTo2(mapOf(p))
+		}
+		ch2["parameters"] = result
+	}
+	c.drop(ch2, path, "title", "summary", "tags", "externalDocs")
+	return ch2
+}
+
+// move parameter enum, default and examples into a string schema
+func (c *asyncAPIConverter) parameterTo2(param map[string]interface{}) map[string]interface{} {
+	if param == nil || len(getString(param, "#/$ref")) > 0 {
+		return param
+	}
+	result := copyExcept(param, "enum", "default", "examples")
+	schema := map[string]interface{}{"type": "string"}
+	for _, k := range []string{"enum", "default", "examples"} {
+		if v, ok := param[k]; ok {
+			schema[k] = v
+		}
+	}
+	result["schema"] = schema
+	return result
+}
+
+// convert a 3.0 operation, and inline its messages from its channel
+func (c *asyncAPIConverter) operationTo2(op map[string]interface{}, opID string, ch map[string]interface{}, chID string, used map[string]bool) map[string]interface{} {
+	path := jsonPointer("operations", opID)
+	op2 := copyExcept(op, "action", "channel", "messages")
+	op2["operationId"] = opID
+	if security, ok := op["security"]; ok {
+		op2["security"] = c.securityTo2(security, path+"/security")
+	}
+	if tags, ok := op["tags"]; ok {
+		op2["tags"] = c.resolveTags(tags)
+	}
+	c.drop(op2, path, "title", "reply")
+
+	var refs []interface{}
+	if list, ok := op["messages"].([]interface{}); ok {
+		refs = list
+	} else {
+		// operation without messages sends or receives all messages of its channel
+		for _, name := range sortedKeys(mapOf(ch["messages"])) {
+			refs = append(refs, map[string]interface{}{"$ref": jsonPointer("channels", chID, "messages", name)})
+		}
+	}
+	var messages []interface{}
+	for _, r := range refs {
+		ref := getString(r, "#/$ref")
+		used[ref] = true
+		prefix := jsonPointer("channels", chID, "messages") + "/"
+		if !strings.HasPrefix(ref, prefix) {
+			c.warn("%s message %s is dropped, because it is not a message of channel %s", path, ref, chID)
+			continue
+		}
+		msg := mapOf(mapOf(ch["messages"])[refName(ref)])
+		if msg == nil {
+			c.warn("%s message %s is not defined", path, ref)
+			continue
+		}
+		messages = append(messages, c.messageTo2(msg))
+	}
+	switch len(messages) {
+	case 0:
+	case 1:
+		op2["message"] = messages[0]
+	default:
+		op2["message"] = map[string]interface{}{"oneOf": messages}
+	}
+	return op2
+}
+
+// move schemaFormat of a multi format schema of payload into message
+func (c *asyncAPIConverter) messageTo2(message map[string]interface{}) map[string]interface{} {
+	if message == nil || len(getString(message, "#/$ref")) > 0 {
+		return message
+	}
+	result := copyExcept(message)
+	if payload := mapOf(message["payload"]); payload != nil {
+		if format := getString(payload, "#/schemaFormat"); len(format) > 0 {
+			result["schemaFormat"] = format
+			result["payload"] = payload["schema"]
+		}
+	}
+	if tags, ok := message["tags"]; ok {
+		result["tags"] = c.resolveTags(tags)
+	}
+	return result
+}
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertAsyncAPI2To3(t *testing.T) {
+	startFakeTCMD(t)
+	root = "convert2to3-test"
+
+	doc := `{
+        "asyncapi": "2.0.0",
+        "info": {"title": "Streetlights API", "version": "1.0.0"},
+        "tags": [{"name": "lights"}],
+        "servers": {
+            "production": {
+                "url": "mqtt://test.mosquitto.org:{port}",
+                "protocol": "mqtt",
+                "variables": {"port": {"default": "1883"}},
+                "security": [{"apiKey": []}]
+            }
+        },
+        "channels": {
+            "light/{streetlightId}/measured": {
+                "parameters": {
+                    "streetlightId": {"description": "Street light ID", "schema": {"type": "string", "enum": ["a", "b"]}}
+                },
+                "subscribe": {
+                    "operationId": "onLightMeasured",
+                    "message": {"$ref": "#/components/messages/lightMeasured"}
+                },
+                "publish": {
+                    "message": {"oneOf": [
+                        {"messageId": "turnOn", "payload": {"type": "object"}},
+                        {"$ref": "#/components/messages/dim"}
+                    ]}
+                }
+            }
+        },
+        "components": {
+            "messages": {
+                "lightMeasured": {"payload": {"type": "integer"}},
+                "dim": {"schemaFormat": "application/vnd.apache.avro;version=1.9.0", "payload": {"type": "int"}}
+            },
+            "securitySchemes": {"apiKey": {"type": "apiKey", "in": "user"}}
+        }
+    }`
+	expected := `{
+        "asyncapi": "3.0.0",
+        "info": {"title": "Streetlights API", "version": "1.0.0", "tags": [{"name": "lights"}]},
+        "servers": {
+            "production": {
+                "host": "test.mosquitto.org:{port}",
+                "protocol": "mqtt",
+                "variables": {"port": {"default": "1883"}},
+                "security": [{"$ref": "#/components/securitySchemes/apiKey"}]
+            }
+        },
+        "channels": {
+            "lightStreetlightIdMeasured": {
+                "address": "light/{streetlightId}/measured",
+                "parameters": {
+                    "streetlightId": {"description": "Street light ID", "enum": ["a", "b"]}
+                },
+                "messages": {
+                    "lightMeasured": {"$ref": "#/components/messages/lightMeasured"},
+                    "turnOn": {"payload": {"type": "object"}},
+                    "dim": {"$ref": "#/components/messages/dim"}
+                }
+            }
+        },
+        "operations": {
+            "onLightMeasured": {
+                "action": "send",
+                "channel": {"$ref": "#/channels/lightStreetlightIdMeasured"},
+                "messages": [{"$ref": "#/channels/lightStreetlightIdMeasured/messages/lightMeasured"}]
+            },
+            "lightStreetlightIdMeasuredPublish": {
+                "action": "receive",
+                "channel": {"$ref": "#/channels/lightStreetlightIdMeasured"},
+                "messages": [
+                    {"$ref": "#/channels/lightStreetlightIdMeasured/messages/turnOn"},
+                    {"$ref": "#/channels/lightStreetlightIdMeasured/messages/dim"}
+                ]
+            }
+        },
+        "components": {
+            "messages": {
+                "lightMeasured": {"payload": {"type": "integer"}},
+                "dim": {"payload": {"schemaFormat": "application/vnd.apache.avro;version=1.9.0", "schema": {"type": "int"}}}
+            },
+            "securitySchemes": {"apiKey": {"type": "apiKey", "in": "user"}}
+        }
+    }`
+	var spec map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(doc), &spec), "test spec should be valid JSON")
+	assert.NoError(t, importAPISpec(spec), "import should not return error")
+	exported, err := exportAPISpec(root)
+	assert.NoError(t, err, "export should not return error")
+
+	converted, warnings, err := convertAsyncAPISpec(exported, "3.0.0")
+	assert.NoError(t, err, "conversion should not return error")
+	assert.Empty(t, warnings, "conversion should be lossless")
+	assert.JSONEq(t, expected, toJSON(t, converted), "converted spec does not match")
+	assert.Equal(t, "2.0.0", getString(exported, "#/asyncapi"), "exported spec should not be modified")
+}
+
+func TestConvertAsyncAPI3To2(t *testing.T) {
+	doc := `{
+        "asyncapi": "3.0.0",
+        "info": {"title": "Streetlights API", "version": "1.0.0", "tags": [{"$ref": "#/components/tags/lights"}]},
+        "servers": {
+            "production": {
+                "host": "test.mosquitto.org:{port}",
+                "pathname": "/mqtt",
+                "title": "Production broker",
+                "protocol": "mqtt",
+                "security": [{"$ref": "#/components/securitySchemes/apiKey"}]
+            }
+        },
+        "channels": {
+            "lightMeasured": {
+                "address": "light/{streetlightId}/measured",
+                "servers": [{"$ref": "#/servers/production"}],
+                "parameters": {"streetlightId": {"enum": ["a", "b"]}},
+                "messages": {
+                    "lightMeasured": {"$ref": "#/components/messages/lightMeasured"},
+                    "unused": {"payload": {"type": "string"}}
+                }
+            }
+        },
+        "operations": {
+            "onLightMeasured": {
+                "action": "receive",
+                "channel": {"$ref": "#/channels/lightMeasured"},
+                "messages": [{"$ref": "#/channels/lightMeasured/messages/lightMeasured"}],
+                "reply": {"channel": {"$ref": "#/channels/lightMeasured"}}
+            },
+            "onLightMeasuredAgain": {
+                "action": "receive",
+                "channel": {"$ref": "#/channels/lightMeasured"}
+            }
+        },
+        "components": {
+            "tags": {"lights": {"name": "lights"}},
+            "messages": {
+                "lightMeasured": {"payload": {"schemaFormat": "application/vnd.apache.avro;version=1.9.0", "schema": {"type": "int"}}}
+            },
+            "securitySchemes": {"apiKey": {"type": "apiKey", "in": "user"}}
+        }
+    }`
+	expected := `{
+        "asyncapi": "2.6.0",
+        "info": {"title": "Streetlights API", "version": "1.0.0"},
+        "tags": [{"name": "lights"}],
+        "servers": {
+            "production": {
+                "url": "test.mosquitto.org:{port}/mqtt",
+                "protocol": "mqtt",
+                "security": [{"apiKey": []}]
+            }
+        },
+        "channels": {
+            "light/{streetlightId}/measured": {
+                "servers": ["production"],
+                "parameters": {"streetlightId": {"schema": {"type": "string", "enum": ["a", "b"]}}},
+                "publish": {
+                    "operationId": "onLightMeasured",
+                    "message": {"$ref": "#/components/messages/lightMeasured"}
+                }
+            }
+        },
+        "components": {
+            "messages": {
+                "lightMeasured": {"schemaFormat": "application/vnd.apache.avro;version=1.9.0", "payload": {"type": "int"}}
+            },
+            "securitySchemes": {"apiKey": {"type": "apiKey", "in": "user"}}
+        }
+    }`
+	var spec map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(doc), &spec), "test spec should be valid JSON")
+
+	converted, warnings, err := convertAsyncAPISpec(spec, "2.6.0")
+	assert.NoError(t, err, "conversion should not return error")
+	assert.JSONEq(t, expected, toJSON(t, converted), "converted spec does not match")
+	assert.Equal(t, []string{
+		"#/servers/production/title is dropped in AsyncAPI 2.6.0",
+		"#/operations/onLightMeasured/reply is dropped in AsyncAPI 2.6.0",
+		"#/operations/onLightMeasuredAgain is dropped, because channel lightMeasured already has a publish operation",
+		"#/channels/lightMeasured/messages/unused is dropped, because no operation refers to it",
+		"#/components/tags is dropped in AsyncAPI 2.6.0",
+	}, warnings, "conversion should warn of lossy translations")
+}
+
+func TestConvertAsyncAPIVersion(t *testing.T) {
+	_, _, err := convertAsyncAPISpec(map[string]interface{}{"openapi": "3.0.0"}, "3.0.0")
+	assert.Error(t, err, "OpenAPI spec should not be converted")
+	_, _, err = convertAsyncAPISpec(map[string]interface{}{"asyncapi": "2.0.0"}, "4.0.0")
+	assert.Error(t, err, "unknown AsyncAPI version should not be supported")
+}
+
+func toJSON(t *testing.T, value interface{}) string {
+	data, err := json.Marshal(value)
+	assert.NoError(t, err, "value should be serializable")
+	return string(data)
+}
@@ -0,0 +1,196 @@
+package cmd
+
+/*
+Copyright © 2020 Yueming Xu <yxu@tibco.com>
+This file is subject to the license terms contained in the license file that is distributed with this file.
+
+Test command: ./tcmdtool diff -i test-data/streetlights.yml -r streetlights
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var diffFormat string
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare an API spec with its definition in TCMD",
+	Long: `Compare an API spec with its definition in TCMD.
+Report JSON pointers that would be added, removed or changed by importing the spec,
+and exit with status 1 if any difference is found.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("diff", input)
+		spec, err := readSpec(input)
+		if err != nil {
+			panic(err)
+		}
+		// set root asset name as input file name if it is not specified
+		if root == "" {
+			root = rootName(input)
+		}
+		changes, err := diffAPISpec(spec, root)
+		if err != nil {
+			panic(err)
+		}
+		if err := printSpecChanges(changes, diffFormat); err != nil {
+			panic(err)
+		}
+		if len(changes) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringVarP(&input, "input", "i", "", "name of the spec file to be compared")
+	diffCmd.Flags().StringVarP(&root, "root", "r", "", "name of root asset created from input file")
+	diffCmd.Flags().StringVarP(&diffFormat, "format", "f", "text", "report format, text or json")
+	diffCmd.MarkFlagRequired("input")
+}
+
+// specChange describes a difference between local spec and TCMD at a JSON pointer
+type specChange struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Local interface{} `json:"local,omitempty"`
+	TCMD  interface{} `json:"tcmd,omitempty"`
+}
+
+// export root asset from TCMD and compare it with a local spec
+func diffAPISpec(spec map[string]interface{}, name string) ([]specChange, error) {
+	var stored interface{} = map[string]interface{}{}
+	asset, err := getRootAsset(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to find root asset %s", name)
+	}
+	if asset != nil {
+		if stored, err = exportAPISpec(name); err != nil {
+			return nil, err
+		}
+	}
+
+	local, err := normalizeSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	if stored, err = normalizeSpec(stored); err != nil {
+		return nil, err
+	}
+	var changes []specChange
+	diffSpecNode(local, stored, "", &changes)
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
+
+// convert typed values, e.g., []string, to generic JSON values, so they can be compared
+func normalizeSpec(spec interface{}) (interface{}, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to serialize spec")
+	}
+	var result interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, errors.Wrap(err, "Failed to deserialize spec")
+	}
+	return result, nil
+}
+
+func diffSpecNode(local, stored interface{}, path string, changes *[]specChange) {
+	switch lv := local.(type) {
+	case map[string]interface{}:
+		sv, ok := stored.(map[string]interface{})
+		if !ok {
+			break
+		}
+		for k, v := range lv {
+			p := path + strings.TrimPrefix(jsonPointer(k), "#")
+			if s, ok := sv[k]; ok {
+				diffSpecNode(v, s, p, changes)
+			} else {
+				*changes = append(*changes, specChange{Op: "added", Path: p, Local: v})
+			}
+		}
+		for k, v := range sv {
+			if _, ok := lv[k]; !ok {
+				*changes = append(*changes, specChange{Op: "removed", Path: path + strings.TrimPrefix(jsonPointer(k), "#"), TCMD: v})
+			}
+		}
+		return
+	case []interface{}:
+		sv, ok := stored.([]interface{})
+		if !ok {
+			break
+		}
+		for i, v := range lv {
+			p := path + "/" + strconv.Itoa(i)
+			if i < len(sv) {
+				diffSpecNode(v, sv[i], p, changes)
+			} else {
+				*changes = append(*changes, specChange{Op: "added", Path: p, Local: v})
+			}
+		}
+		for i := len(lv); i < len(sv); i++ {
+			*changes = append(*changes, specChange{Op: "removed", Path: path + "/" + strconv.Itoa(i), TCMD: sv[i]})
+		}
+		return
+	}
+	if !reflect.DeepEqual(local, stored) {
+		*changes = append(*changes, specChange{Op: "changed", Path: path, Local: local, TCMD: stored})
+	}
+}
+
+func printSpecChanges(changes []specChange, format string) error {
+	if format == "json" {
+		if changes == nil {
+			changes = []specChange{}
+		}
+		data, err := json.MarshalIndent(changes, "", "    ")
+		if err != nil {
+			return errors.Wrap(err, "Failed to serialize diff report")
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(changes) == 0 {
+		fmt.Println("no difference found")
+		return nil
+	}
+	for _, c := range changes {
+		switch c.Op {
+		case "changed":
+			fmt.Printf("changed %s: %s -> %s\n", c.Path, diffValue(c.TCMD), diffValue(c.Local))
+		default:
+			fmt.Printf("%-7s %s\n", c.Op, c.Path)
+		}
+	}
+	fmt.Printf("%d difference(s) found\n", len(changes))
+	return nil
+}
+
+// short JSON of a value for text report
+func diffValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	if len(data) > 80 {
+		return string(data[:77]) + "..."
+	}
+	return string(data)
+}
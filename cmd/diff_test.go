@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffSpecNode(t *testing.T) {
+	local := `{
+        "asyncapi": "2.0.0",
+        "channels": {
+            "light/measured": {
+                "description": "new description"
+            },
+            "light/on": {}
+        },
+        "servers": {
+            "production": {
+                "url": "localhost:1883",
+                "security": [{"apiKey": []}]
+            }
+        }
+    }`
+	stored := `{
+        "asyncapi": "2.0.0",
+        "channels": {
+            "light/measured": {
+                "description": "old description"
+            },
+            "light/dim": {}
+        },
+        "servers": {
+            "production": {
+                "url": "localhost:1883",
+                "security": [{"apiKey": []}, {"oauth": []}]
+            }
+        }
+    }`
+	var lv, sv interface{}
+	json.Unmarshal([]byte(local), &lv)
+	json.Unmarshal([]byte(stored), &sv)
+
+	var changes []specChange
+	diffSpecNode(lv, sv, "", &changes)
+	result := make(map[string]string)
+	for _, c := range changes {
+		result[c.Path] = c.Op
+	}
+	expected := map[string]string{
+		"/channels/light~1measured/description": "changed",
+		"/channels/light~1on":                   "added",
+		"/channels/light~1dim":                  "removed",
+		"/servers/production/security/1":        "removed",
+	}
+	assert.Equal(t, expected, result, "spec changes do not match")
+
+	changes = nil
+	diffSpecNode(lv, lv, "", &changes)
+	assert.Empty(t, changes, "same spec should not have any change")
+}
@@ -8,25 +8,32 @@ Test command: ./tcmdtool export -r slack_events_api
 */
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 
-	"github.com/ghodss/yaml"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
 )
 
 var (
-	output string
-	format string
+	output         string
+	format         string
+	asyncAPITarget string
+	deref          bool
 )
 
 // exportCmd represents the export command
 var exportCmd = &cobra.Command{
 	Use:   "export",
 	Short: "export API spec from TCMD",
-	Long:  `export API spec from TCMD`,
+	Long: `export API spec from TCMD.
+An AsyncAPI spec is exported in the version it was imported, unless --asyncapi-version specifies
+a 2.x or 3.0 version to convert it to. Constructs that cannot be converted losslessly are reported as warnings.
+With --dereference, component $refs are replaced by their definitions, so the exported spec can be used by tools
+that do not resolve $refs. A recursive $ref is kept when its component is already being expanded.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("export", root)
 
@@ -35,10 +42,29 @@ var exportCmd = &cobra.Command{
 			output = fmt.Sprintf("%s.%s", root, format)
 		}
 
-		spec, err := exportAsyncAPISpec(root)
+		spec, err := exportAPISpec(root)
 		if err != nil {
 			panic(err)
 		}
+		if asyncAPITarget != "" {
+			converted, warnings, err := convertAsyncAPISpec(spec, asyncAPITarget)
+			if err != nil {
+				panic(err)
+			}
+			for _, w := range warnings {
+				fmt.Println("warning:", w)
+			}
+			spec = converted
+		}
+		if deref {
+			m, ok := spec.(map[string]interface{})
+			if !ok {
+				panic(errors.Errorf("spec type %T is not a map", spec))
+			}
+			if err := expandComponents(m); err != nil {
+				panic(err)
+			}
+		}
 		data, err := encode(spec)
 		if err != nil {
 			panic(err)
@@ -52,91 +78,81 @@ func init() {
 	rootCmd.AddCommand(exportCmd)
 
 	exportCmd.Flags().StringVarP(&root, "root", "r", "", "name of root asset to be exported")
-	exportCmd.Flags().StringVarP(&input, "output", "o", "", "name of the spec file to be exported")
+	exportCmd.Flags().StringVarP(&output, "output", "o", "", "name of the spec file to be exported")
 	exportCmd.Flags().StringVarP(&format, "format", "f", "json", "output file format, json or yaml")
+	exportCmd.Flags().StringVar(&asyncAPITarget, "asyncapi-version", "", "convert AsyncAPI spec to a 2.x or 3.0 version, e.g., 3.0.0")
+	exportCmd.Flags().BoolVar(&deref, "dereference", false, "replace component $refs by their definitions")
 	exportCmd.MarkFlagRequired("root")
 }
 
-// fetch asset data type of a specified ID
-func getAssetDataTypeByID(id int) (*DataType, error) {
-	path := fmt.Sprintf("%s/%s/datatype/%d", TCDataspace, TCDataset, id)
-	resp, err := get(path, nil)
+// export API spec using the exporter of the spec kind recorded by a child of the root asset
+func exportAPISpec(name string) (interface{}, error) {
+	asset, err := getRootAsset(name)
 	if err != nil {
-		return nil, errors.Wrap(err, "Failed TCMD request")
+		return nil, errors.Wrapf(err, "Failed to find root asset %s", name)
 	}
-
-	var result DataType
-	err = json.Unmarshal(resp, &result)
-	if err != nil {
-		return nil, errors.Wrap(err, "Failed to unmarshal TCMD response")
+	if asset == nil {
+		return nil, errors.Errorf("Root asset %s does not exist", name)
 	}
-	return &result, nil
-}
-
-// fetch asset of a specified ID
-func getAssetByID(id int) (*Asset, error) {
-	path := fmt.Sprintf("asset/%d", id)
-	resp, err := get(path, nil)
+	refScope = name
+	labelPaths = map[int]string{asset.ID: "#"}
+	labelSequences = make(map[string]int)
+	children, err := getChildrenAsset(asset.ID)
 	if err != nil {
-		return nil, errors.Wrap(err, "Failed TCMD request")
+		return nil, errors.Wrapf(err, "Failed to fetch children of root asset %s", name)
 	}
-
-	var result Asset
-	err = json.Unmarshal(resp, &result)
-	if err != nil {
-		return nil, errors.Wrap(err, "Failed to unmarshal TCMD response")
+	for _, c := range children {
+		if c.Label == "openapi" {
+			fmt.Println("export openapi spec", name)
+			return exportOpenAPISpec(name)
+		}
 	}
-	return &result, nil
+	return exportAsyncAPISpec(name)
 }
 
-// fetch asset of a specified name
-func getAssetByName(name string) (*Asset, error) {
-	params := map[string]string{
-		"predicate": fmt.Sprintf("name='%s'", name),
-	}
-	resp, err := get("asset", params)
-	if err != nil {
-		return nil, errors.Wrap(err, "Failed TCMD request")
-	}
-
-	var result []Asset
-	err = json.Unmarshal(resp, &result)
-	if err != nil {
-		return nil, errors.Wrap(err, "Failed to unmarshal TCMD response")
-	}
+// fetch asset data type of a specified ID
+func getAssetDataTypeByID(id int) (*DataType, error) {
+	return client.GetDataType(tcmdContext(), id)
+}
 
-	if len(result) > 0 {
-		return &result[0], nil
-	}
+// fetch asset of a specified ID
+func getAssetByID(id int) (*Asset, error) {
+	return client.GetAsset(tcmdContext(), id)
+}
 
-	return nil, nil
+// fetch root asset of a specified name, i.e., an asset without parent, so a nested asset of the same name is never matched
+func getRootAsset(name string) (*Asset, error) {
+	return client.FindChildAsset(tcmdContext(), 0, name)
 }
 
-// fetch children assets of a specified parent
+// fetch children assets of a specified parent in the order of their sequence
 func getChildrenAsset(id int) ([]Asset, error) {
-	params := map[string]string{
-		"predicate": fmt.Sprintf("parent='%d'", id),
-	}
-	resp, err := get("asset", params)
-	if err != nil {
-		return nil, errors.Wrap(err, "Failed TCMD request")
-	}
-
-	var result []Asset
-	err = json.Unmarshal(resp, &result)
+	children, err := client.ListChildren(tcmdContext(), id)
 	if err != nil {
-		return nil, errors.Wrap(err, "Failed to unmarshal TCMD response")
+		return nil, err
 	}
+	sortChildren(id, children)
+	return children, nil
+}
 
-	if len(result) > 0 {
-		return result, nil
-	}
-	return nil, nil
+// fetch assets typed by a specified data type
+func getAssetsByDataType(tid int) ([]Asset, error) {
+	return client.ListAssetsByDataType(tcmdContext(), tid)
 }
 
+// encode spec with keys in the order of the imported spec
 func encode(data interface{}) ([]byte, error) {
+	ordered := orderSpec(data, "#")
 	if format == "yaml" {
-		return yaml.Marshal(data)
+		return yaml.Marshal(ordered)
+	}
+	var buf bytes.Buffer
+	if err := writeOrderedJSON(&buf, ordered); err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "    "); err != nil {
+		return nil, err
 	}
-	return json.MarshalIndent(data, "", "    ")
+	return out.Bytes(), nil
 }
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yxuco/tcmdtool/tcmd"
+)
+
+// fakeTCMD is an in-memory TCMD server that supports the asset and {dataspace}/{dataset}/datatype requests used by
+// import and export. Queries support predicates on name and parent, e.g., name='info' and parent='12'.
+type fakeTCMD struct {
+	sync.Mutex
+	assets    map[int]Asset
+	dataTypes map[int]DataType
+	nextID    int
+	// IDs of assets and data types in creation order indexed by the fields used in predicates
+	index map[string][]int
+	// failed returns true for a request that should fail with a server error
+	failed func(r *http.Request) bool
+}
+
+var predicatePattern = regexp.MustCompile(`(\w+)='((?:[^']|'')*)'`)
+
+// unquote a predicate value matched by predicatePattern
+func unquote(value string) string {
+	return strings.Replace(value, "''", "'", -1)
+}
+
+func (f *fakeTCMD) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	path := strings.Trim(r.URL.Path, "/")
+	dataTypePath := TCDataspace + "/" + TCDataset + "/datatype"
+	isDataType := path == dataTypePath || strings.HasPrefix(path, dataTypePath+"/")
+	if !isDataType && path != "asset" && !strings.HasPrefix(path, "asset/") {
+		http.NotFound(w, r)
+		return
+	}
+	if f.failed != nil && f.failed(r) {
+		http.Error(w, "injected failure", http.StatusInternalServerError)
+		return
+	}
+	id := 0
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		id, _ = strconv.Atoi(path[i+1:])
+	}
+	switch {
+	case r.Method == http.MethodPost && isDataType:
+		var dt DataType
+		json.NewDecoder(r.Body).Decode(&dt)
+		f.nextID++
+		dt.ID = f.nextID
+		f.dataTypes[dt.ID] = dt
+		f.addIndex(dt.ID, "datatype:name", dt.Name)
+		json.NewEncoder(w).Encode(dt)
+	case r.Method == http.MethodPost:
+		var asset Asset
+		json.NewDecoder(r.Body).Decode(&asset)
+		f.nextID++
+		asset.ID = f.nextID
+		f.assets[asset.ID] = asset
+		f.addIndex(asset.ID, "name", asset.Name)
+		f.addIndex(asset.ID, "parent", asset.Parent)
+		json.NewEncoder(w).Encode(asset)
+	case r.Method == http.MethodPut:
+		var asset Asset
+		json.NewDecoder(r.Body).Decode(&asset)
+		if old, ok := f.assets[id]; ok {
+			if old.Name != asset.Name {
+				f.addIndex(id, "name", asset.Name)
+			}
+			if old.Parent != asset.Parent {
+				f.addIndex(id, "parent", asset.Parent)
+			}
+		}
+		f.assets[id] = asset
+		json.NewEncoder(w).Encode(asset)
+	case r.Method == http.MethodDelete && isDataType:
+		delete(f.dataTypes, id)
+	case r.Method == http.MethodDelete:
+		delete(f.assets, id)
+	case r.Method == http.MethodGet && isDataType && id > 0:
+		json.NewEncoder(w).Encode(f.dataTypes[id])
+	case r.Method == http.MethodGet && isDataType:
+		result := []DataType{}
+		for _, i := range f.query(r, "datatype:") {
+			if dt, ok := f.dataTypes[i]; ok && f.match(r, map[string]string{"name": dt.Name}) {
+				result = append(result, dt)
+			}
+		}
+		json.NewEncoder(w).Encode(result)
+	case r.Method == http.MethodGet && id > 0:
+		json.NewEncoder(w).Encode(f.assets[id])
+	default:
+		// return assets in creation order
+		result := []Asset{}
+		for _, i := range f.query(r, "") {
+			if a, ok := f.assets[i]; ok && f.match(r, map[string]string{"name": a.Name, "parent": a.Parent, "assetDataType": a.AssetDataType}) {
+				result = append(result, a)
+			}
+		}
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+func (f *fakeTCMD) addIndex(id int, field, value string) {
+	key := field + "=" + value
+	f.index[key] = append(f.index[key], id)
+}
+
+// returns IDs of candidates of a query in creation order, using the index of the first indexed field of the predicate
+func (f *fakeTCMD) query(r *http.Request, prefix string) []int {
+	for _, m := range predicatePattern.FindAllStringSubmatch(r.URL.Query().Get("predicate"), -1) {
+		if m[1] == "name" || m[1] == "parent" {
+			return dedupe(f.index[prefix+m[1]+"="+unquote(m[2])])
+		}
+	}
+	ids := make([]int, f.nextID)
+	for i := range ids {
+		ids[i] = i + 1
+	}
+	return ids
+}
+
+// remove IDs added again after an update, and sort them in creation order
+func dedupe(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	var result []int
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	sort.Ints(result)
+	return result
+}
+
+// returns true if all conditions of the predicate query parameter match the specified fields
+func (f *fakeTCMD) match(r *http.Request, fields map[string]string) bool {
+	for _, m := range predicatePattern.FindAllStringSubmatch(r.URL.Query().Get("predicate"), -1) {
+		if fields[m[1]] != unquote(m[2]) {
+			return false
+		}
+	}
+	return true
+}
+
+// start a fake TCMD server, and set it as the client of the test
+func startFakeTCMD(t *testing.T) *fakeTCMD {
+	fake := &fakeTCMD{
+		assets:    make(map[int]Asset),
+		dataTypes: make(map[int]DataType),
+		index:     make(map[string][]int),
+	}
+	server := httptest.NewServer(fake)
+	saved := client
+	// retry injected failures without waiting
+	client = tcmd.NewClient(server.URL, tcmd.WithRetry(tcmd.RetryPolicy{MaxRetries: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}))
+	AssetDataTypes = make(map[string]int)
+	AssetDataTypeIDs = make(map[int]string)
+	stats = importStats{}
+	externalDefinitions = nil
+	sourceKeyOrder = nil
+	t.Cleanup(func() {
+		server.Close()
+		client = saved
+	})
+	return fake
+}
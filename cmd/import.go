@@ -8,57 +8,61 @@ Test command: ./tcmdtool import -i test-data/slack_events_api.json
 */
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"path/filepath"
-	"strings"
-	"time"
+	"os"
+	"strconv"
 
 	"github.com/ghodss/yaml"
 	"github.com/pkg/errors"
-
 	"github.com/spf13/cobra"
 )
 
 var (
-	input string
-	root  string
+	input     string
+	root      string
+	upsert    bool
+	bundleDir string
+	roots     map[string]string
 )
 
 // importCmd represents the import command
 var importCmd = &cobra.Command{
 	Use:   "import",
 	Short: "Import an API spec to TCMD",
-	Long:  `Import an API spec to TCMD`,
+	Long: `Import an API spec to TCMD.
+Assets and data types created by the import are deleted if any step fails, so a failed import leaves nothing behind.
+A summary reports the JSON pointer of each failed spec node, and the command exits with status 1 if the import failed.
+With --dir, every AsyncAPI and OpenAPI spec in a directory or glob pattern is imported as a separate transaction,
+and its root asset is named by --roots or by its file name without extension.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if bundleDir != "" {
+			results, err := importBundle(bundleDir, roots)
+			if err != nil {
+				panic(err)
+			}
+			if failed := printBundleSummary(results); failed > 0 {
+				os.Exit(1)
+			}
+			return
+		}
+		if input == "" {
+			panic(errors.New("either --input or --dir must be specified"))
+		}
+
 		fmt.Println("import", input)
-		data, err := ioutil.ReadFile(input)
+		spec, err := readSpec(input)
 		if err != nil {
 			panic(err)
 		}
 		// set root asset name as input file name if it is not specified
 		if root == "" {
-			fn := filepath.Base(input)
-			root = fn[0:strings.Index(fn, ".")]
-		}
-		var spec map[string]interface{}
-		if err = decode(data, &spec); err != nil {
-			panic(err)
+			root = rootName(input)
 		}
-		if spec["asyncapi"] != nil {
-			fmt.Printf("Read asyncapi spec version %s\n", spec["asyncapi"])
-			if err := importAsyncAPISpec(spec); err != nil {
-				panic(err)
-			}
-		}
-		if spec["openapi"] != nil {
-			fmt.Printf("Read openapi spec version %s\n", spec["openapi"])
-			if err := importOpenAPISpec(spec); err != nil {
-				panic(err)
-			}
+		err = importWithRollback(spec)
+		printImportSummary(err)
+		if err != nil {
+			os.Exit(1)
 		}
 	},
 }
@@ -68,71 +72,31 @@ func init() {
 
 	importCmd.Flags().StringVarP(&input, "input", "i", "", "name of the file to be imported")
 	importCmd.Flags().StringVarP(&root, "root", "r", "", "root asset name to be created from input file")
-	importCmd.MarkFlagRequired("input")
+	importCmd.Flags().BoolVar(&upsert, "upsert", false, "update assets of the same name and parent if they exist, and create only missing assets")
+	importCmd.Flags().StringVarP(&bundleDir, "dir", "d", "", "directory or glob pattern of spec files to be imported")
+	importCmd.Flags().StringToStringVar(&roots, "roots", nil, "root asset names of spec files in --dir, e.g., v1.2-events.yml=events")
+	importCmd.Flags().IntVar(&concurrency, "concurrency", 1, "max number of sibling spec nodes imported concurrently, e.g., 8")
 }
 
-func get(path string, params map[string]string) ([]byte, error) {
-	reqURL := fmt.Sprintf("%s/%s", url, path)
-	reqAuth := fmt.Sprintf("Basic %s", authtoken)
-
-	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
-	if err != nil {
-		return nil, errors.Wrapf(err, "Failed to create GET request %s", reqURL)
-	}
-
-	if params != nil {
-		q := req.URL.Query()
-		for k, v := range params {
-			q.Add(k, v)
-		}
-		req.URL.RawQuery = q.Encode()
-	}
-	fmt.Printf("GET %s using token %s\n", req.URL, reqAuth)
-
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Authorization", reqAuth)
-
-	client := &http.Client{Timeout: time.Duration(5) * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, errors.Wrapf(err, "Failed http GET %s", req.URL)
-	}
-	fmt.Printf("TCMD GET status: %d\n", resp.StatusCode)
-
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return nil, errors.Errorf("HTTP GET returned status %d", resp.StatusCode)
-	}
-	return ioutil.ReadAll(resp.Body)
-}
-
-func post(path string, data interface{}) ([]byte, error) {
-	reqURL := fmt.Sprintf("%s/%s", url, path)
-	reqAuth := fmt.Sprintf("Basic %s", authtoken)
-	fmt.Printf("POST %s using token %s\n", reqURL, reqAuth)
-
-	jsonReq, err := json.Marshal(data)
-	if err != nil {
-		return nil, errors.Wrap(err, "Failed to serialize assets")
-	}
-	fmt.Println(string(jsonReq))
-
-	req, err := http.NewRequest(http.MethodPost, reqURL, bytes.NewBuffer(jsonReq))
-	if err != nil {
-		return nil, errors.Wrapf(err, "Failed to create POST request %s", reqURL)
+// import asyncapi or openapi spec, and then definitions of its external refs
+func importAPISpec(spec map[string]interface{}) error {
+	startWorkers(concurrency)
+	refScope = root
+	var err error
+	switch {
+	case spec["asyncapi"] != nil:
+		fmt.Printf("Read asyncapi spec version %s\n", spec["asyncapi"])
+		err = importAsyncAPISpec(spec)
+	case spec["openapi"] != nil:
+		fmt.Printf("Read openapi spec version %s\n", spec["openapi"])
+		err = importOpenAPISpec(spec)
+	default:
+		return errors.New("input is not an asyncapi or openapi spec")
 	}
-
-	req.Header.Set("Content-Type", "application/json; charset=utf-8")
-	req.Header.Set("Authorization", reqAuth)
-	client := &http.Client{Timeout: time.Duration(5) * time.Second}
-	resp, err := client.Do(req)
 	if err != nil {
-		return nil, errors.Wrapf(err, "Failed http POST %s", reqURL)
+		return err
 	}
-	fmt.Printf("TCMD POST status: %d\n", resp.StatusCode)
-
-	defer resp.Body.Close()
-	return ioutil.ReadAll(resp.Body)
+	return importExternalDefinitions()
 }
 
 func decode(data []byte, v interface{}) error {
@@ -146,76 +110,115 @@ func decode(data []byte, v interface{}) error {
 
 // returns data type ID if it exists, 0 otherwise
 func getAssetDataType(dataType string) int {
-	path := fmt.Sprintf("%s/%s/datatype", TCDataspace, TCDataset)
-	params := map[string]string{
-		"predicate": fmt.Sprintf("name='%s'", dataType),
-	}
-	if resp, err := get(path, params); err == nil {
-		var result []DataType
-		if err = json.Unmarshal(resp, &result); err == nil {
-			if len(result) > 0 {
-				return result[0].ID
-			}
-		}
+	if result, err := client.FindDataTypeByName(tcmdContext(), dataType); err == nil && result != nil {
+		return result.ID
 	}
 	return 0
 }
 
 // find or create asset datatype by name, and return the ID
 func findOrCreateAssetDataType(dataType string, complexType bool) (int, error) {
-	tid := getAssetDataType(dataType)
-	if tid > 0 {
-		return tid, nil
+	result, err := client.FindDataTypeByName(tcmdContext(), dataType)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Failed to find data type %s", dataType)
+	}
+	if result != nil {
+		return result.ID, nil
 	}
 	return createAssetDataType(dataType, complexType)
 }
 
 // create new asset datatype by name, and return the ID
 func createAssetDataType(dataType string, complexType bool) (int, error) {
-	// create asset data type
-	path := fmt.Sprintf("%s/%s/datatype", TCDataspace, TCDataset)
 	data := DataType{
 		Name:        dataType,
 		Label:       dataType,
 		BuiltIn:     false,
 		ComplexType: complexType,
 	}
-	resp, err := post(path, data)
+	result, err := client.CreateDataType(tcmdContext(), data)
 	if err != nil {
 		return 0, err
 	}
-	var result DataType
-	if err = json.Unmarshal(resp, &result); err != nil {
-		return 0, err
-	}
+	journal.dataTypeCreated(result.ID)
 	return result.ID, nil
 }
 
-// returns asset ID if it exists, 0 otherwise
-func getAsset(name string) int {
-	params := map[string]string{
-		"predicate": fmt.Sprintf("name='%s'", name),
-	}
-	if resp, err := get("asset", params); err == nil {
-		var result []Asset
-		if err = json.Unmarshal(resp, &result); err == nil {
-			if len(result) > 0 {
-				return result[0].ID
-			}
+// create asset and return the ID. In upsert mode, update and return existing asset of the same name and parent.
+func createAsset(asset Asset) (int, error) {
+	pointer := setSequence(&asset)
+	var id int
+	if upsert {
+		var err error
+		if id, err = upsertAsset(asset); err != nil {
+			return 0, err
 		}
+	} else {
+		result, err := client.CreateAsset(tcmdContext(), asset)
+		if err != nil {
+			return 0, err
+		}
+		journal.assetCreated(result.ID)
+		count(&stats.created)
+		id = result.ID
 	}
-	return 0
+	if len(pointer) > 0 {
+		setSourcePointer(id, pointer)
+	}
+	return id, nil
 }
 
-// create or find asset by name, and return the ID
-func createAsset(asset Asset) (int, error) {
-	resp, err := post("asset", asset)
-	if err != nil {
-		return 0, err
+// update asset of the same name and parent if it exists, or create it otherwise
+func upsertAsset(asset Asset) (int, error) {
+	parent := 0
+	if len(asset.Parent) > 0 {
+		pid, err := strconv.Atoi(asset.Parent)
+		if err != nil {
+			return 0, errors.Wrapf(err, "Invalid parent %s of asset %s", asset.Parent, asset.Name)
+		}
+		parent = pid
+	}
+
+	var existing *Asset
+	if syncTree != nil {
+		// sync mode matches assets of the cached tree
+		existing = syncTree.match(parent, asset.Name)
+	} else {
+		var err error
+		if existing, err = client.FindChildAsset(tcmdContext(), parent, asset.Name); err != nil {
+			return 0, err
+		}
 	}
-	var result Asset
-	if err = json.Unmarshal(resp, &result); err != nil {
+	if existing == nil {
+		result, err := client.CreateAsset(tcmdContext(), asset)
+		if err != nil {
+			return 0, err
+		}
+		journal.assetCreated(result.ID)
+		count(&stats.created)
+		return result.ID, nil
+	}
+
+	if !assetChanged(existing, &asset) {
+		count(&stats.unchanged)
+		return existing.ID, nil
+	}
+	asset.ID = existing.ID
+	fmt.Printf("update asset %d -> %s\n", asset.ID, asset.Name)
+	if _, err := client.UpdateAsset(tcmdContext(), asset); err != nil {
 		return 0, err
 	}
-	return result.ID, nil
+	journal.assetUpdated(existing)
+	count(&stats.updated)
+	return existing.ID, nil
+}
+
+// returns true if content of an existing asset is different from the imported asset
+func assetChanged(existing *Asset, asset *Asset) bool {
+	return existing.Label != asset.Label ||
+		existing.Description != asset.Description ||
+		existing.Comment != asset.Comment ||
+		existing.AssetType != asset.AssetType ||
+		existing.AssetDataType != asset.AssetDataType ||
+		existing.Sequence != asset.Sequence
 }
@@ -8,16 +8,8 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-var (
-	testid = 211
-)
-
-func init() {
-	cfgFile = "../.tcmdtool"
-	initConfig()
-}
-
-func TestTCMDPostAsset(t *testing.T) {
+// post a test asset to the fake TCMD, and return the result
+func postTestAsset(t *testing.T) Asset {
 	asset := Asset{
 		Name:        "test-api",
 		Label:       "test-api",
@@ -32,26 +24,32 @@ func TestTCMDPostAsset(t *testing.T) {
 		IsDisabled:              false,
 		Version:                 "1.0.0",
 	}
-	resp, err := post("asset", asset)
+	resp, err := client.Post(tcmdContext(), "asset", asset)
 	assert.NoError(t, err, "POST asset should not return error %v", err)
 	assert.NotNil(t, resp, "POST asset should not return nil")
-	fmt.Println(string(resp))
 
 	var result Asset
 	err = json.Unmarshal(resp, &result)
 	assert.NoError(t, err, "POST asset result is not a valid asset %v", err)
+	return result
+}
 
-	testid = result.ID
+func TestTCMDPostAsset(t *testing.T) {
+	fake := startFakeTCMD(t)
+	result := postTestAsset(t)
+	assert.Lessf(t, 0, result.ID, "New asset ID %d should be greater than 0", result.ID)
 	assert.Equal(t, "test-api", result.Label, "Asset label does not match")
+	assert.Equal(t, "1.0.0", fake.assets[result.ID].Version, "Asset should be stored")
 }
 
 func TestTCMDGet(t *testing.T) {
+	startFakeTCMD(t)
+	testid := postTestAsset(t).ID
 	path := fmt.Sprintf("asset/%d", testid)
 
-	resp, err := get(path, nil)
+	resp, err := client.Get(tcmdContext(), path, nil)
 	assert.NoError(t, err, "GET %s should not return error %v", path, err)
 	assert.NotNil(t, resp, "GET %s should not return nil", path)
-	fmt.Println(string(resp))
 
 	var asset Asset
 	err = json.Unmarshal(resp, &asset)
@@ -59,9 +57,14 @@ func TestTCMDGet(t *testing.T) {
 
 	assert.Equal(t, testid, asset.ID, "Asset ID does not match")
 	assert.Equal(t, "test-api", asset.Label, "Asset label does not match")
+
+	children, err := client.Get(tcmdContext(), "asset", map[string]string{"predicate": fmt.Sprintf("parent='%d'", testid)})
+	assert.NoError(t, err, "QUERY children should not return error %v", err)
+	assert.JSONEq(t, "[]", string(children), "Asset should not have children")
 }
 
 func TestTCMDPostDataType(t *testing.T) {
+	startFakeTCMD(t)
 	data := DataType{
 		Name:        "test-type",
 		Label:       "test-type",
@@ -69,33 +72,42 @@ func TestTCMDPostDataType(t *testing.T) {
 		BuiltIn:     false,
 		ComplexType: false,
 	}
-	resp, err := post(fmt.Sprintf("%s/%s/datatype", TCDataspace, TCDataset), data)
+	resp, err := client.Post(tcmdContext(), fmt.Sprintf("%s/%s/datatype", TCDataspace, TCDataset), data)
 	assert.NoError(t, err, "POST data type should not return error %v", err)
 	assert.NotNil(t, resp, "POST data type should not return nil")
-	fmt.Println(string(resp))
 
 	var result DataType
 	err = json.Unmarshal(resp, &result)
 	assert.NoError(t, err, "POST data type result is not a valid JSON %v", err)
 
-	assert.Lessf(t, 0, result.ID, "New data type ID $d should be greater than 0", result.ID)
+	assert.Lessf(t, 0, result.ID, "New data type ID %d should be greater than 0", result.ID)
 	assert.Equal(t, "test-type", result.Label, "Data type label does not match")
+
+	_, err = client.Post(tcmdContext(), "unknown/dataset/datatype", data)
+	assert.Error(t, err, "POST data type of unknown dataset should return error")
 }
 
 func TestTCMDQuery(t *testing.T) {
+	fake := startFakeTCMD(t)
+	for _, name := range []string{"Undefined", "string"} {
+		_, err := createAssetDataType(name, false)
+		assert.NoError(t, err, "data type %s should be created", name)
+	}
+
 	path := fmt.Sprintf("%s/%s/datatype", TCDataspace, TCDataset)
 	params := map[string]string{
 		"predicate": "name='Undefined'",
 	}
-	resp, err := get(path, params)
+	resp, err := client.Get(tcmdContext(), path, params)
 	assert.NoError(t, err, "QUERY %s should not return error %v", path, err)
 	assert.NotNil(t, resp, "QUERY %s should not return nil", path)
-	fmt.Println(string(resp))
 
 	var result []DataType
 	err = json.Unmarshal(resp, &result)
 	assert.NoError(t, err, "QUERY %s result is not a valid array %v", path, err)
-	assert.Equal(t, 999, result[0].ID, "data type ID does not match")
+	if assert.Len(t, result, 1, "query should return data type of the name") {
+		assert.Equal(t, "Undefined", fake.dataTypes[result[0].ID].Name, "data type ID does not match")
+	}
 }
 
 func TestExtractProperties(t *testing.T) {
@@ -137,3 +149,27 @@ func TestExtractProperties(t *testing.T) {
 }`
 	assert.Equal(t, expected, result, "data does not match")
 }
+
+func TestTCMDQueryQuotedName(t *testing.T) {
+	startFakeTCMD(t)
+	rid, err := createAsset(Asset{Name: "o'reilly", Label: "o'reilly", AssetType: AssetTypes["JSON Element"]})
+	assert.NoError(t, err, "root asset should be created")
+	cid, err := createAsset(Asset{Name: "it's", Label: "it's", Parent: fmt.Sprint(rid), AssetType: AssetTypes["JSON Element"]})
+	assert.NoError(t, err, "child asset should be created")
+	_, err = createAssetDataType("#/components/schemas/it's", false)
+	assert.NoError(t, err, "data type should be created")
+
+	asset, err := getRootAsset("o'reilly")
+	assert.NoError(t, err, "root asset of name with quote should be queried")
+	if assert.NotNil(t, asset, "root asset of name with quote should be found") {
+		assert.Equal(t, rid, asset.ID, "root asset ID does not match")
+	}
+	child, err := client.FindChildAsset(tcmdContext(), rid, "it's")
+	assert.NoError(t, err, "child asset of name with quote should be queried")
+	if assert.NotNil(t, child, "child asset of name with quote should be found") {
+		assert.Equal(t, cid, child.ID, "child asset ID does not match")
+	}
+	dt, err := client.FindDataTypeByName(tcmdContext(), "#/components/schemas/it's")
+	assert.NoError(t, err, "data type of name with quote should be queried")
+	assert.NotNil(t, dt, "data type of name with quote should be found")
+}
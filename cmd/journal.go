@@ -0,0 +1,123 @@
+package cmd
+
+/*
+Copyright © 2020 Yueming Xu <yxu@tibco.com>
+This file is subject to the license terms contained in the license file that is distributed with this file.
+*/
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// journalEntry records a TCMD change made by import, so it can be rolled back
+type journalEntry struct {
+	// kind is either asset or datatype
+	kind string
+	id   int
+	// content of an updated asset before the change, or nil if the object is created
+	previous *Asset
+}
+
+// importJournal records assets and data types created or updated during one import run
+type importJournal struct {
+	mu      sync.Mutex
+	entries []journalEntry
+}
+
+// journal is set while an import is in progress
+var journal *importJournal
+
+func (j *importJournal) assetCreated(id int) {
+	if j != nil {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		j.entries = append(j.entries, journalEntry{kind: "asset", id: id})
+	}
+}
+
+func (j *importJournal) assetUpdated(previous *Asset) {
+	if j != nil {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		p := *previous
+		j.entries = append(j.entries, journalEntry{kind: "asset", id: p.ID, previous: &p})
+	}
+}
+
+func (j *importJournal) dataTypeCreated(id int) {
+	if j != nil {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		j.entries = append(j.entries, journalEntry{kind: "datatype", id: id})
+	}
+}
+
+// undo recorded changes in reverse order, so child assets are deleted before their parents.
+// It continues after a failure, and returns all errors that cannot be rolled back.
+func (j *importJournal) rollback() error {
+	var failed []string
+	for i := len(j.entries) - 1; i >= 0; i-- {
+		e := j.entries[i]
+		var err error
+		switch {
+		case e.previous != nil:
+			fmt.Printf("rollback: restore asset %d -> %s\n", e.id, e.previous.Name)
+			_, err = client.UpdateAsset(tcmdContext(), *e.previous)
+		case e.kind == "datatype":
+			fmt.Printf("rollback: delete datatype %d\n", e.id)
+			if err = deleteAssetDataType(e.id); err == nil {
+				forgetDataType(e.id)
+			}
+		default:
+			fmt.Printf("rollback: delete asset %d\n", e.id)
+			err = deleteAsset(e.id)
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s %d: %v", e.kind, e.id, err))
+		}
+	}
+	if len(failed) > 0 {
+		return errors.Errorf("failed to rollback %d change(s): %s", len(failed), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// remove a deleted data type from the caches, so a later import in the same run does not refer to it
+func forgetDataType(id int) {
+	dataTypesLock.Lock()
+	defer dataTypesLock.Unlock()
+	for name, tid := range AssetDataTypes {
+		if tid == id {
+			delete(AssetDataTypes, name)
+		}
+	}
+	delete(AssetDataTypeIDs, id)
+}
+
+// import spec as a transaction, i.e., undo all changes made by the import if any step fails
+func importWithRollback(spec map[string]interface{}) (err error) {
+	journal = &importJournal{}
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("import panic: %v", r)
+		}
+		j := journal
+		journal = nil
+		if err == nil || recorder != nil {
+			// nothing is created in dry-run mode
+			return
+		}
+		fmt.Printf("import failed: %v\n", err)
+		fmt.Printf("rollback %d change(s) of import\n", len(j.entries))
+		if rerr := j.rollback(); rerr != nil {
+			fmt.Printf("rollback failed: %v\n", rerr)
+			// keep the import error as cause, so its failed spec nodes can be reported
+			err = errors.WithMessage(err, rerr.Error())
+		}
+	}()
+	return importAPISpec(spec)
+}
@@ -6,31 +6,1020 @@ This file is subject to the license terms contained in the license file that is
 */
 import (
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 )
 
+// HTTP methods of OpenAPI path item
+var pathOperations = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
 func importOpenAPISpec(spec map[string]interface{}) error {
-	return importAPIPaths(spec)
+	if err := initializeAssetDataTypes(); err != nil {
+		return err
+	}
+
+	var errs specErrors
+	rid, err := createOpenAPIAsset(spec)
+	if err != nil {
+		errs.add(err)
+		return errs.result()
+	}
+	if openapi, ok := spec["openapi"]; ok {
+		_, err := createSimpleAsset("openapi", fmt.Sprintf("%v", openapi), rid, "string")
+		errs.add(err, "openapi")
+	}
+
+	var g importGroup
+	if info, ok := spec["info"]; ok {
+		g.run(func() error { return createInfoAsset(info, rid) }, "info")
+	}
+
+	if components, ok := spec["components"]; ok {
+		g.run(func() error { return createOpenAPIComponentsAsset(components, rid) }, "components")
+	}
+
+	if servers, ok := spec["servers"]; ok {
+		g.run(func() error { return createOpenAPIServersAsset(servers, rid) }, "servers")
+	}
+
+	// paths are optional since OpenAPI 3.1, e.g., in a spec of webhooks or components only
+	for _, key := range []string{"paths", "webhooks"} {
+		if paths, ok := spec[key]; ok {
+			key := key
+			g.run(func() error { return createPathsAsset(key, paths, rid) }, key)
+		}
+	}
+
+	if security, ok := spec["security"]; ok {
+		g.run(func() error { return createSecurityRequirementAsset(security, rid) }, "security")
+	}
+
+	if tags, ok := spec["tags"]; ok {
+		g.run(func() error { return createTagsAsset(tags, rid) }, "tags")
+	}
+
+	if externalDocs, ok := spec["externalDocs"]; ok {
+		g.run(func() error { return createExternalDocsAsset(externalDocs, rid) }, "externalDocs")
+	}
+	errs.add(g.wait())
+	return errs.result()
 }
 
-func importAPIPaths(spec map[string]interface{}) error {
-	paths, ok := spec["paths"].(map[string]interface{})
+func createOpenAPIAsset(doc map[string]interface{}) (int, error) {
+	comment := extractExtraProperties(doc, []string{"openapi", "info", "servers", "paths", "webhooks", "components", "security", "tags", "externalDocs"})
+	asset := Asset{
+		Name:                    root,
+		Label:                   root,
+		Comment:                 comment,
+		AssetType:               AssetTypes["JSON Element"],
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	return createAsset(asset)
+}
+
+// create asset of paths or webhooks, which are both maps of path items
+func createPathsAsset(name string, items interface{}, parent int) error {
+	paths, ok := items.(map[string]interface{})
 	if !ok {
-		return errors.New("paths are not defined in openapi spec")
+		return errors.Errorf("%s type %T is not a map", name, items)
+	}
+	asset := Asset{
+		Name:                    name,
+		Label:                   name,
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
 	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+
+	var g importGroup
 	for k, v := range paths {
-		ops := v.(map[string]interface{})
-		fmt.Printf("import path %s - ", k)
-		for o := range ops {
-			fmt.Print(o, " ")
+		k, v := k, v
+		g.run(func() error { return createPathItemAsset(k, v, pid) }, k)
+	}
+	return g.wait()
+}
+
+func createPathItemAsset(name string, item interface{}, parent int) error {
+	props, ok := item.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("No properties for path %s", name)
+	}
+	exclude := append([]string{"$ref", "description", "parameters"}, pathOperations...)
+	asset := Asset{
+		Name:                    name,
+		Label:                   name,
+		Description:             getString(item, "#/description"),
+		Comment:                 extractExtraProperties(props, exclude),
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	tid, err := refDataType(item)
+	if err != nil {
+		return err
+	}
+	if tid > 0 {
+		asset.AssetDataType = strconv.Itoa(tid)
+	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+
+	var errs specErrors
+	if params, ok := props["parameters"]; ok {
+		errs.add(createAPIParametersAsset(params, pid), "parameters")
+	}
+
+	for _, op := range pathOperations {
+		if val, ok := props[op]; ok {
+			errs.add(createAPIOperationAsset(op, val, pid), op)
+		}
+	}
+	return errs.result()
+}
+
+func createAPIOperationAsset(name string, operation interface{}, parent int) error {
+	op, ok := operation.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("operation %s type %T is not a map", name, operation)
+	}
+	comment := extractExtraProperties(op, []string{"description", "tags", "externalDocs", "parameters", "requestBody", "responses", "security"})
+	asset := Asset{
+		Name:                    name,
+		Label:                   name,
+		Description:             getString(operation, "#/description"),
+		Comment:                 comment,
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+
+	var errs specErrors
+	if tags, ok := op["tags"].([]interface{}); ok {
+		// operation tags are tag names, so convert them to tag objects
+		tagList := make([]interface{}, 0, len(tags))
+		for _, t := range tags {
+			tagList = append(tagList, map[string]interface{}{"name": fmt.Sprintf("%v", t)})
+		}
+		errs.add(createTagsAsset(tagList, pid), "tags")
+	}
+
+	if externalDocs := getRef(operation, "#/externalDocs"); externalDocs != nil {
+		errs.add(createExternalDocsAsset(externalDocs, pid), "externalDocs")
+	}
+
+	if params := getRef(operation, "#/parameters"); params != nil {
+		errs.add(createAPIParametersAsset(params, pid), "parameters")
+	}
+
+	if body := getRef(operation, "#/requestBody"); body != nil {
+		tid, err := refDataType(body)
+		if err == nil {
+			err = createRequestBodyAsset("requestBody", body, tid, pid)
+		}
+		errs.add(err, "requestBody")
+	}
+
+	if responses := getRef(operation, "#/responses"); responses != nil {
+		errs.add(createResponsesAsset(responses, pid), "responses")
+	}
+
+	if security, ok := op["security"]; ok {
+		errs.add(createSecurityRequirementAsset(security, pid), "security")
+	}
+	return errs.result()
+}
+
+// OpenAPI parameters is an array of parameter objects or refs
+func createAPIParametersAsset(params interface{}, parent int) error {
+	ps, ok := params.([]interface{})
+	if !ok {
+		return errors.Errorf("parameters %T is not an array", params)
+	}
+	asset := Asset{
+		Name:                    "parameters",
+		Label:                   "parameters",
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		AssetDataType:           strconv.Itoa(dataTypeID("array")),
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+
+	var errs specErrors
+	for i, p := range ps {
+		tid, err := refDataType(p)
+		if err == nil {
+			name, label := parameterName(p, i)
+			err = createParameterObjectAsset(name, label, p, tid, pid)
+		}
+		errs.add(err, strconv.Itoa(i))
+	}
+	return errs.result()
+}
+
+// unnamedParameter matches asset names of parameters without name in a parameter list
+var unnamedParameter = regexp.MustCompile(`^parameter-\d+$`)
+
+// returns asset name and label of the i-th item of a parameter list. A parameter is identified by its name and location,
+// so its asset is named by both, e.g., query:id, and labeled by its name. A parameter without name is named by its index.
+func parameterName(param interface{}, i int) (string, string) {
+	if ref := getString(param, "#/$ref"); len(ref) > 0 {
+		name := ref[strings.LastIndex(ref, "/")+1:]
+		return name, name
+	}
+	name := getString(param, "#/name")
+	if len(name) == 0 {
+		name = fmt.Sprintf("parameter-%d", i)
+		return name, name
+	}
+	if in := getString(param, "#/in"); len(in) > 0 {
+		return in + ":" + name, name
+	}
+	return name, name
+}
+
+// create parameter or header object, which are the same except that header does not have name and in
+func createAPIParameterAsset(name string, parameter interface{}, tid int, parent int) error {
+	return createParameterObjectAsset(name, name, parameter, tid, parent)
+}
+
+// create parameter or header object of specified asset name and label
+func createParameterObjectAsset(name, label string, parameter interface{}, tid int, parent int) error {
+	param, ok := parameter.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("parameter %s type %T is not a map", name, parameter)
+	}
+	exclude := []string{"$ref", "description", "schema", "content"}
+	if getString(parameter, "#/name") == label {
+		// name is the same as asset label, so do not store it in comment
+		exclude = append(exclude, "name")
+	}
+	comment := extractExtraProperties(param, exclude)
+	asset := Asset{
+		Name:                    name,
+		Label:                   label,
+		Description:             getString(parameter, "#/description"),
+		Comment:                 comment,
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	if tid > 0 {
+		asset.AssetDataType = strconv.Itoa(tid)
+	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+
+	var errs specErrors
+	if schema := getRef(parameter, "#/schema"); schema != nil {
+		tid, err := refDataType(schema)
+		if err == nil {
+			err = createSchemaAsset("schema", schema, tid, pid, false)
 		}
-		fmt.Println()
+		errs.add(err, "schema")
+	}
+
+	if content := getRef(parameter, "#/content"); content != nil {
+		errs.add(createContentAsset(content, pid), "content")
+	}
+	return errs.result()
+}
+
+func createRequestBodyAsset(name string, body interface{}, tid int, parent int) error {
+	rb, ok := body.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("request body %s type %T is not a map", name, body)
+	}
+	comment := extractExtraProperties(rb, []string{"$ref", "description", "content"})
+	asset := Asset{
+		Name:                    name,
+		Label:                   name,
+		Description:             getString(body, "#/description"),
+		Comment:                 comment,
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	if tid > 0 {
+		asset.AssetDataType = strconv.Itoa(tid)
+	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+
+	if content := getRef(body, "#/content"); content != nil {
+		return atNode(createContentAsset(content, pid), "content")
 	}
 	return nil
 }
 
+func createResponsesAsset(responses interface{}, parent int) error {
+	rm, ok := responses.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("responses type %T is not a map", responses)
+	}
+	asset := Asset{
+		Name:                    "responses",
+		Label:                   "responses",
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+
+	var errs specErrors
+	for k, v := range rm {
+		tid, err := refDataType(v)
+		if err == nil {
+			err = createResponseAsset(k, v, tid, pid)
+		}
+		errs.add(err, k)
+	}
+	return errs.result()
+}
+
+func createResponseAsset(name string, response interface{}, tid int, parent int) error {
+	resp, ok := response.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("response %s type %T is not a map", name, response)
+	}
+	comment := extractExtraProperties(resp, []string{"$ref", "description", "headers", "content"})
+	asset := Asset{
+		Name:                    name,
+		Label:                   name,
+		Description:             getString(response, "#/description"),
+		Comment:                 comment,
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	if tid > 0 {
+		asset.AssetDataType = strconv.Itoa(tid)
+	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+
+	var errs specErrors
+	if headers := getRef(response, "#/headers"); headers != nil {
+		errs.add(createAPIHeadersAsset(headers, pid), "headers")
+	}
+
+	if content := getRef(response, "#/content"); content != nil {
+		errs.add(createContentAsset(content, pid), "content")
+	}
+	return errs.result()
+}
+
+func createAPIHeadersAsset(headers interface{}, parent int) error {
+	hm, ok := headers.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("headers type %T is not a map", headers)
+	}
+	asset := Asset{
+		Name:                    "headers",
+		Label:                   "headers",
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+
+	var errs specErrors
+	for k, v := range hm {
+		tid, err := refDataType(v)
+		if err == nil {
+			err = createAPIParameterAsset(k, v, tid, pid)
+		}
+		errs.add(err, k)
+	}
+	return errs.result()
+}
+
+// content maps media type, e.g., application/json, to media type object
+func createContentAsset(content interface{}, parent int) error {
+	cm, ok := content.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("content type %T is not a map", content)
+	}
+	asset := Asset{
+		Name:                    "content",
+		Label:                   "content",
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+
+	var errs specErrors
+	for k, v := range cm {
+		mt, ok := v.(map[string]interface{})
+		if !ok {
+			errs.add(errors.Errorf("media type %s type %T is not a map", k, v), k)
+			continue
+		}
+		asset := Asset{
+			Name:                    k,
+			Label:                   k,
+			Comment:                 extractExtraProperties(mt, []string{"schema"}),
+			Parent:                  strconv.Itoa(pid),
+			AssetType:               AssetTypes["JSON Element"],
+			DataElementAutoAssigned: false,
+			IsDisabled:              false,
+		}
+		mid, err := createAsset(asset)
+		if err != nil {
+			errs.add(err, k)
+			continue
+		}
+		if schema := getRef(v, "#/schema"); schema != nil {
+			tid, err := refDataType(schema)
+			if err == nil {
+				err = createSchemaAsset("schema", schema, tid, mid, false)
+			}
+			errs.add(err, k, "schema")
+		}
+	}
+	return errs.result()
+}
+
+// OpenAPI servers is an array of server objects, each server asset is named by its url
+func createOpenAPIServersAsset(servers interface{}, parent int) error {
+	ss, ok := servers.([]interface{})
+	if !ok {
+		return errors.Errorf("servers %T is not an array", servers)
+	}
+	asset := Asset{
+		Name:                    "servers",
+		Label:                   "servers",
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		AssetDataType:           strconv.Itoa(dataTypeID("array")),
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+
+	var errs specErrors
+	for i, s := range ss {
+		server, ok := s.(map[string]interface{})
+		if !ok {
+			errs.add(errors.Errorf("server %T is not a map", s), strconv.Itoa(i))
+			continue
+		}
+		name := getString(server, "#/url")
+		asset := Asset{
+			Name:                    name,
+			Label:                   name,
+			Description:             getString(server, "#/description"),
+			Comment:                 extractExtraProperties(server, []string{"url", "description"}),
+			Parent:                  strconv.Itoa(pid),
+			AssetType:               AssetTypes["JSON Element"],
+			DataElementAutoAssigned: false,
+			IsDisabled:              false,
+		}
+		_, err := createAsset(asset)
+		errs.add(err, strconv.Itoa(i))
+	}
+	return errs.result()
+}
+
+func createOpenAPIComponentsAsset(components interface{}, parent int) error {
+	cm, ok := components.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("components type %T is not a map", components)
+	}
+	asset := Asset{
+		Name:                    "components",
+		Label:                   "components",
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	pid, err := createAsset(asset)
+	if err != nil {
+		return err
+	}
+
+	var g importGroup
+	for cat, list := range cm {
+		cat, list := cat, list
+		g.run(func() error { return createComponentCategoryAsset(cat, list, pid, createOpenAPIComponentAsset) }, cat)
+	}
+	return g.wait()
+}
+
+// create asset of a reusable data type of a category of OpenAPI components
+func createOpenAPIComponentAsset(cat, k string, v interface{}, cid int) error {
+	tid, err := setRef(jsonPointer("components", cat, k))
+	if err != nil {
+		return err
+	}
+
+	switch cat {
+	case "schemas":
+		return createSchemaAsset(k, v, tid, cid, false)
+	case "responses":
+		return createResponseAsset(k, v, tid, cid)
+	case "parameters", "headers":
+		return createAPIParameterAsset(k, v, tid, cid)
+	case "requestBodies":
+		return createRequestBodyAsset(k, v, tid, cid)
+	case "securitySchemes":
+		return createSecuritySchemeAsset(k, v, tid, cid)
+	default:
+		// examples, links and callbacks are stored as JSON
+		return createComponentValueAsset(k, v, tid, cid)
+	}
+}
+
+// store a component as JSON value with its component data type
+func createComponentValueAsset(name string, value interface{}, tid int, parent int) error {
+	vm, ok := value.(map[string]interface{})
+	if !ok {
+		return errors.Errorf("component %s type %T is not a map", name, value)
+	}
+	asset := Asset{
+		Name:                    name,
+		Label:                   name,
+		Description:             getString(value, "#/description"),
+		Comment:                 extractExtraProperties(vm, []string{"description"}),
+		Parent:                  strconv.Itoa(parent),
+		AssetType:               AssetTypes["JSON Element"],
+		DataElementAutoAssigned: false,
+		IsDisabled:              false,
+	}
+	if tid > 0 {
+		asset.AssetDataType = strconv.Itoa(tid)
+	}
+	_, err := createAsset(asset)
+	return err
+}
+
 func cleanOpenAPISpec(spec interface{}) error {
-	fmt.Println("clean OpenAPI spec not implemented")
+	if err := cleanRootAsset(root); err != nil {
+		return err
+	}
+	return cleanComponentDataTypes(spec)
+}
+
+func exportOpenAPISpec(name string) (interface{}, error) {
+	spec := make(map[string]interface{})
+	asset, err := getRootAsset(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to find root asset %s", name)
+	}
+	if asset == nil {
+		return nil, errors.Errorf("Root asset %s does not exist", name)
+	}
+
+	if len(asset.Comment) > 0 {
+		extractComment(asset.Comment, spec)
+	}
+
+	children, err := getChildrenAsset(asset.ID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to fetch children of root asset %s", name)
+	}
+	for _, child := range children {
+		switch child.Label {
+		case "openapi":
+			err = extractSimpleAsset(&child, spec)
+		case "info":
+			err = extractInfoAsset(&child, spec)
+		case "components":
+			err = extractOpenAPIComponentsAsset(&child, spec)
+		case "servers":
+			err = extractOpenAPIServersAsset(&child, spec)
+		case "paths", "webhooks":
+			err = extractPathsAsset(&child, spec)
+		case "security":
+			err = extractSecurityRequirementAsset(&child, spec)
+		case "tags":
+			err = extractTagsAsset(&child, spec)
+		case "externalDocs":
+			err = extractExternalDocsAsset(&child, spec)
+		default:
+			fmt.Println("Unknown child element", child.Label)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return spec, nil
+}
+
+// fetch children of an exported asset, so the export fails instead of returning a partial spec if TCMD fails
+func getExportChildren(child *Asset) ([]Asset, error) {
+	children, err := getChildrenAsset(child.ID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to fetch children of %s asset %d", child.Label, child.ID)
+	}
+	return children, nil
+}
+
+func extractPathsAsset(child *Asset, parent map[string]interface{}) error {
+	paths := make(map[string]interface{})
+	parent[child.Label] = paths
+
+	children, err := getExportChildren(child)
+	if err != nil {
+		return err
+	}
+	for _, c := range children {
+		if err := extractPathItemAsset(&c, paths); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractPathItemAsset(child *Asset, parent map[string]interface{}) error {
+	item := make(map[string]interface{})
+	parent[child.Label] = item
+
+	if ok := setComponentRef(child, item); ok {
+		return nil
+	}
+	if len(child.Description) > 0 {
+		item["description"] = child.Description
+	}
+	if len(child.Comment) > 0 {
+		extractComment(child.Comment, item)
+	}
+
+	children, err := getExportChildren(child)
+	if err != nil {
+		return err
+	}
+	for _, c := range children {
+		switch c.Label {
+		case "parameters":
+			err = extractAPIParametersAsset(&c, item)
+		case "get", "put", "post", "delete", "options", "head", "patch", "trace":
+			err = extractAPIOperationAsset(&c, item)
+		default:
+			fmt.Printf("path child type %s is not implemented\n", c.Label)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractAPIOperationAsset(child *Asset, parent map[string]interface{}) error {
+	operation := make(map[string]interface{})
+	parent[child.Label] = operation
+
+	if len(child.Description) > 0 {
+		operation["description"] = child.Description
+	}
+	if len(child.Comment) > 0 {
+		extractComment(child.Comment, operation)
+	}
+
+	children, err := getExportChildren(child)
+	if err != nil {
+		return err
+	}
+	for _, c := range children {
+		switch c.Label {
+		case "tags":
+			err = extractTagNamesAsset(&c, operation)
+		case "externalDocs":
+			err = extractExternalDocsAsset(&c, operation)
+		case "parameters":
+			err = extractAPIParametersAsset(&c, operation)
+		case "requestBody":
+			err = extractRequestBodyAsset(&c, operation, false)
+		case "responses":
+			err = extractResponsesAsset(&c, operation)
+		case "security":
+			err = extractSecurityRequirementAsset(&c, operation)
+		default:
+			fmt.Printf("operation child type %s is not implemented\n", c.Label)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// operation tags are exported as a list of tag names
+func extractTagNamesAsset(child *Asset, parent map[string]interface{}) error {
+	children, err := getExportChildren(child)
+	if err != nil {
+		return err
+	}
+	tags := make([]interface{}, 0, len(children))
+	for _, c := range children {
+		tags = append(tags, c.Label)
+	}
+	parent["tags"] = tags
+	return nil
+}
+
+func extractAPIParametersAsset(child *Asset, parent map[string]interface{}) error {
+	children, err := getExportChildren(child)
+	if err != nil {
+		return err
+	}
+	params := make([]interface{}, 0, len(children))
+	for _, c := range children {
+		param := make(map[string]interface{})
+		if err := extractAPIParameterAsset(&c, param, false, true); err != nil {
+			return err
+		}
+		params = append(params, param)
+	}
+	parent["parameters"] = params
+	return nil
+}
+
+// extract parameter or header object. parameter name is set from asset label if it is not in comment,
+// unless the parameter did not have a name.
+func extractAPIParameterAsset(child *Asset, param map[string]interface{}, isComponent bool, withName bool) error {
+	if !isComponent {
+		if ok := setComponentRef(child, param); ok {
+			return nil
+		}
+	}
+	if withName && !(child.Name == child.Label && unnamedParameter.MatchString(child.Name)) {
+		param["name"] = child.Label
+	}
+	if len(child.Description) > 0 {
+		param["description"] = child.Description
+	}
+	if len(child.Comment) > 0 {
+		extractComment(child.Comment, param)
+	}
+
+	children, err := getExportChildren(child)
+	if err != nil {
+		return err
+	}
+	for _, c := range children {
+		switch c.Label {
+		case "schema":
+			err = extractSchemaAsset(&c, param, false)
+		case "content":
+			err = extractContentAsset(&c, param)
+		default:
+			fmt.Printf("parameter child type %s is not implemented\n", c.Label)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractRequestBodyAsset(child *Asset, parent map[string]interface{}, isComponent bool) error {
+	body := make(map[string]interface{})
+	parent[child.Label] = body
+
+	if !isComponent {
+		if ok := setComponentRef(child, body); ok {
+			return nil
+		}
+	}
+	if len(child.Description) > 0 {
+		body["description"] = child.Description
+	}
+	if len(child.Comment) > 0 {
+		extractComment(child.Comment, body)
+	}
+
+	children, err := getExportChildren(child)
+	if err != nil {
+		return err
+	}
+	for _, c := range children {
+		if c.Label == "content" {
+			if err := extractContentAsset(&c, body); err != nil {
+				return err
+			}
+		} else {
+			fmt.Printf("request body child type %s is not implemented\n", c.Label)
+		}
+	}
+	return nil
+}
+
+func extractResponsesAsset(child *Asset, parent map[string]interface{}) error {
+	responses := make(map[string]interface{})
+	parent["responses"] = responses
+
+	children, err := getExportChildren(child)
+	if err != nil {
+		return err
+	}
+	for _, c := range children {
+		if err := extractResponseAsset(&c, responses, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractResponseAsset(child *Asset, parent map[string]interface{}, isComponent bool) error {
+	response := make(map[string]interface{})
+	parent[child.Label] = response
+
+	if !isComponent {
+		if ok := setComponentRef(child, response); ok {
+			return nil
+		}
+	}
+	if len(child.Description) > 0 {
+		response["description"] = child.Description
+	}
+	if len(child.Comment) > 0 {
+		extractComment(child.Comment, response)
+	}
+
+	children, err := getExportChildren(child)
+	if err != nil {
+		return err
+	}
+	for _, c := range children {
+		switch c.Label {
+		case "headers":
+			err = extractAPIHeadersAsset(&c, response)
+		case "content":
+			err = extractContentAsset(&c, response)
+		default:
+			fmt.Printf("response child type %s is not implemented\n", c.Label)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractAPIHeadersAsset(child *Asset, parent map[string]interface{}) error {
+	headers := make(map[string]interface{})
+	parent["headers"] = headers
+
+	children, err := getExportChildren(child)
+	if err != nil {
+		return err
+	}
+	for _, c := range children {
+		header := make(map[string]interface{})
+		headers[c.Label] = header
+		if err := extractAPIParameterAsset(&c, header, false, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractContentAsset(child *Asset, parent map[string]interface{}) error {
+	content := make(map[string]interface{})
+	parent["content"] = content
+
+	children, err := getExportChildren(child)
+	if err != nil {
+		return err
+	}
+	for _, c := range children {
+		mediaType := make(map[string]interface{})
+		content[c.Label] = mediaType
+		if len(c.Comment) > 0 {
+			extractComment(c.Comment, mediaType)
+		}
+		schemas, err := getExportChildren(&c)
+		if err != nil {
+			return err
+		}
+		if len(schemas) > 0 {
+			if err := extractSchemaAsset(&schemas[0], mediaType, false); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func extractOpenAPIServersAsset(child *Asset, parent map[string]interface{}) error {
+	children, err := getExportChildren(child)
+	if err != nil {
+		return err
+	}
+	servers := make([]interface{}, 0, len(children))
+	for _, c := range children {
+		server := map[string]interface{}{
+			"url": c.Label,
+		}
+		if len(c.Description) > 0 {
+			server["description"] = c.Description
+		}
+		if len(c.Comment) > 0 {
+			extractComment(c.Comment, server)
+		}
+		servers = append(servers, server)
+	}
+	parent["servers"] = servers
+	return nil
+}
+
+func extractOpenAPIComponentsAsset(child *Asset, parent map[string]interface{}) error {
+	components := make(map[string]interface{})
+	parent[child.Label] = components
+
+	categories, err := getChildrenAsset(child.ID)
+	if err != nil {
+		return errors.Wrap(err, "Failed to fetch component categories")
+	}
+	for _, cat := range categories {
+		category := make(map[string]interface{})
+		comps, err := getChildrenAsset(cat.ID)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to fetch components of category %s", cat.Label)
+		}
+		components[cat.Label] = category
+		for _, c := range comps {
+			switch cat.Label {
+			case "schemas":
+				err = extractSchemaAsset(&c, category, true)
+			case "responses":
+				err = extractResponseAsset(&c, category, true)
+			case "parameters", "headers":
+				param := make(map[string]interface{})
+				category[c.Label] = param
+				err = extractAPIParameterAsset(&c, param, true, cat.Label == "parameters")
+			case "requestBodies":
+				err = extractRequestBodyAsset(&c, category, true)
+			case "securitySchemes":
+				err = extractSecuritySchemeAsset(&c, category)
+			default:
+				err = extractComponentValueAsset(&c, category)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// extract a component stored as JSON value
+func extractComponentValueAsset(child *Asset, parent map[string]interface{}) error {
+	value := make(map[string]interface{})
+	parent[child.Label] = value
+	if len(child.Description) > 0 {
+		value["description"] = child.Description
+	}
+	if len(child.Comment) > 0 {
+		extractComment(child.Comment, value)
+	}
 	return nil
 }
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenAPIWebhooksRoundTrip(t *testing.T) {
+	startFakeTCMD(t)
+	root = "webhooks-test"
+
+	doc := `{
+        "openapi": "3.1.0",
+        "info": {"title": "Webhooks test", "version": "1.0.0"},
+        "webhooks": {
+            "newPet": {
+                "post": {
+                    "requestBody": {
+                        "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Pet"}}}
+                    },
+                    "responses": {"200": {"description": "Return 200 if the data is received"}}
+                }
+            }
+        },
+        "components": {
+            "schemas": {
+                "Pet": {"type": "object", "properties": {"name": {"type": "string"}}}
+            }
+        }
+    }`
+	var spec map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(doc), &spec), "test spec should be valid JSON")
+
+	assert.NoError(t, importAPISpec(spec), "import should not return error for spec without paths")
+	exported, err := exportAPISpec(root)
+	assert.NoError(t, err, "export should not return error")
+	assertSameJSON(t, spec, exported, "exported spec should be the same as the imported spec")
+	assert.Nil(t, getRef(exported, "#/paths"), "paths should not be added")
+}
+
+func TestOpenAPIParametersRoundTrip(t *testing.T) {
+	fake := startFakeTCMD(t)
+	root = "parameters-test"
+
+	doc := `{
+        "openapi": "3.0.0",
+        "info": {"title": "Parameters test", "version": "1.0.0"},
+        "paths": {
+            "/pets/{id}": {
+                "get": {
+                    "parameters": [
+                        {"name": "id", "in": "path", "required": true, "schema": {"type": "string"}},
+                        {"name": "id", "in": "query", "schema": {"type": "integer"}},
+                        {"in": "header", "schema": {"type": "string"}}
+                    ],
+                    "responses": {"200": {"description": "A pet"}}
+                }
+            }
+        }
+    }`
+	var spec map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(doc), &spec), "test spec should be valid JSON")
+
+	assert.NoError(t, importAPISpec(spec), "import should not return error")
+	upsert = true
+	t.Cleanup(func() { upsert = false })
+	assert.NoError(t, importAPISpec(spec), "upsert should not return error")
+
+	names := make(map[string]int)
+	for _, a := range fake.assets {
+		names[a.Name]++
+	}
+	assert.Equal(t, 1, names["path:id"], "path parameter should be named by location and name")
+	assert.Equal(t, 1, names["query:id"], "query parameter should be named by location and name")
+
+	exported, err := exportAPISpec(root)
+	assert.NoError(t, err, "export should not return error")
+	assertSameJSON(t, spec, exported, "parameters of the same name should not overwrite each other")
+}
+
+func TestOpenAPIExportFailure(t *testing.T) {
+	fake := startFakeTCMD(t)
+	root = "failure-test"
+
+	doc := `{
+        "openapi": "3.0.0",
+        "info": {"title": "Failure test", "version": "1.0.0"},
+        "paths": {
+            "/pets": {
+                "get": {
+                    "responses": {"200": {"description": "A list of pets"}}
+                }
+            }
+        }
+    }`
+	var spec map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(doc), &spec), "test spec should be valid JSON")
+	assert.NoError(t, importAPISpec(spec), "import should not return error")
+
+	predicate := ""
+	for id, a := range fake.assets {
+		if a.Name == "responses" {
+			predicate = fmt.Sprintf("parent='%d'", id)
+		}
+	}
+	fake.Lock()
+	fake.failed = func(r *http.Request) bool {
+		return r.URL.Query().Get("predicate") == predicate
+	}
+	fake.Unlock()
+	_, err := exportAPISpec(root)
+	assert.Error(t, err, "export should fail if children of an asset cannot be fetched")
+}
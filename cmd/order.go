@@ -0,0 +1,301 @@
+package cmd
+
+/*
+Copyright © 2020 Yueming Xu <yxu@tibco.com>
+This file is subject to the license terms contained in the license file that is distributed with this file.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+// sourceKeyOrder maps JSON pointers of objects in the spec file being imported to their keys in the source order.
+// It is set by readSpec.
+var sourceKeyOrder map[string][]string
+
+// sourcePointers maps IDs of imported assets to JSON pointers of their keys in the source spec.
+// It is guarded by sourcePointersLock, since assets are created by concurrent imports.
+var (
+	sourcePointers     map[int]string
+	sourcePointersLock sync.RWMutex
+)
+
+// labelPaths maps IDs of exported assets to paths of their labels from the root asset, e.g., #/channels/light~1measured
+var labelPaths map[int]string
+
+// labelSequences maps label paths of exported assets to their sequence, or 0 if the sequence is not recorded
+var labelSequences map[string]int
+
+// keys of spec objects in the order of a typical spec, used to place keys that are not imported as assets
+var specKeys = []string{
+	"asyncapi", "openapi", "id", "$ref", "type", "info", "name", "title", "summary", "version", "url", "email",
+	"host", "protocol", "protocolVersion", "pathname", "servers", "variables", "defaultContentType", "channels",
+	"paths", "webhooks", "address", "operations", "action", "channel", "operationId", "messageId", "format", "enum", "const",
+	"default", "minimum", "exclusiveMinimum", "maximum", "exclusiveMaximum", "multipleOf", "minLength",
+	"maxLength", "pattern", "minItems", "maxItems", "uniqueItems", "in", "description", "termsOfService",
+	"contact", "license", "required", "deprecated", "style", "explode", "allowEmptyValue", "location", "scheme",
+	"bearerFormat", "flows", "openIdConnectUrl", "get", "put", "post", "delete", "options", "head", "patch",
+	"trace", "subscribe", "publish", "security", "tags", "externalDocs", "parameters", "requestBody", "responses",
+	"content", "headers", "correlationId", "contentType", "schemaFormat", "payload", "schema", "items",
+	"properties", "additionalProperties", "allOf", "oneOf", "anyOf", "not", "examples", "example", "traits",
+	"bindings", "message", "messages", "reply", "components",
+}
+
+// append a token to a JSON pointer
+func appendPointer(pointer, token string) string {
+	return pointer + strings.TrimPrefix(jsonPointer(token), "#")
+}
+
+// returns keys of each object in a JSON or YAML document in the order they are written, or nil if it cannot be parsed
+func readKeyOrder(data []byte) map[string][]string {
+	order := make(map[string][]string)
+	if err := readJSONKeys(json.NewDecoder(bytes.NewReader(data)), "#", order); err == nil {
+		return order
+	}
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil
+	}
+	order = make(map[string][]string)
+	readYAMLKeys(doc, "#", order)
+	return order
+}
+
+func readJSONKeys(dec *json.Decoder, pointer string, order map[string][]string) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch t {
+	case json.Delim('{'):
+		var keys []string
+		for dec.More() {
+			kt, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			k := fmt.Sprint(kt)
+			keys = append(keys, k)
+			if err := readJSONKeys(dec, appendPointer(pointer, k), order); err != nil {
+				return err
+			}
+		}
+		order[pointer] = keys
+	case json.Delim('['):
+		for i := 0; dec.More(); i++ {
+			if err := readJSONKeys(dec, appendPointer(pointer, strconv.Itoa(i)), order); err != nil {
+				return err
+			}
+		}
+	default:
+		return nil
+	}
+	// read the closing delimiter
+	_, err = dec.Token()
+	return err
+}
+
+func readYAMLKeys(node interface{}, pointer string, order map[string][]string) {
+	switch v := node.(type) {
+	case yaml.MapSlice:
+		keys := make([]string, 0, len(v))
+		for _, item := range v {
+			k := fmt.Sprint(item.Key)
+			keys = append(keys, k)
+			readYAMLKeys(item.Value, appendPointer(pointer, k), order)
+		}
+		order[pointer] = keys
+	case []interface{}:
+		for i, c := range v {
+			readYAMLKeys(c, appendPointer(pointer, strconv.Itoa(i)), order)
+		}
+	}
+}
+
+// set sequence of an imported asset to the position of its key in the source spec, and return the JSON pointer of the key.
+// Properties of a schema are created under the schema asset, so they are also looked up in the properties of the parent.
+func setSequence(asset *Asset) string {
+	if len(asset.Parent) == 0 {
+		if asset.Name == root {
+			sourcePointersLock.Lock()
+			sourcePointers = make(map[int]string)
+			sourcePointersLock.Unlock()
+			return "#"
+		}
+		return ""
+	}
+	pid, err := strconv.Atoi(asset.Parent)
+	if err != nil {
+		return ""
+	}
+	sourcePointersLock.RLock()
+	parent, ok := sourcePointers[pid]
+	sourcePointersLock.RUnlock()
+	if !ok {
+		return ""
+	}
+	for _, p := range []string{parent, appendPointer(parent, "properties")} {
+		for i, k := range sourceKeyOrder[p] {
+			if k == asset.Label {
+				asset.Sequence = i + 1
+				return appendPointer(p, k)
+			}
+		}
+	}
+	return ""
+}
+
+func setSourcePointer(id int, pointer string) {
+	sourcePointersLock.Lock()
+	defer sourcePointersLock.Unlock()
+	sourcePointers[id] = pointer
+}
+
+// sort children of an exported asset by their sequence, and record their label paths.
+// Children without sequence are placed after the others.
+func sortChildren(parent int, children []Asset) {
+	sort.SliceStable(children, func(i, j int) bool {
+		si, sj := children[i].Sequence, children[j].Sequence
+		return si > 0 && (sj == 0 || si < sj)
+	})
+	path, ok := labelPaths[parent]
+	if !ok {
+		return
+	}
+	for _, c := range children {
+		p := appendPointer(path, c.Label)
+		labelPaths[c.ID] = p
+		labelSequences[p] = c.Sequence
+	}
+}
+
+// returns label path of the value of a key in an exported object of a specified label path.
+// Properties of a schema are exported from children of the schema asset.
+func childLabelPath(path, key string) string {
+	p := appendPointer(path, key)
+	if _, ok := labelSequences[p]; !ok && key == "properties" {
+		return path
+	}
+	return p
+}
+
+// returns keys of an exported object of a specified label path, so keys of assets are placed in the order of their
+// sequence, and the other keys fill the remaining positions in the order of a typical spec
+func orderKeys(obj map[string]interface{}, path string) []string {
+	var sequenced, others []string
+	for k := range obj {
+		if labelSequences[appendPointer(path, k)] > 0 {
+			sequenced = append(sequenced, k)
+		} else {
+			others = append(others, k)
+		}
+	}
+	sort.Slice(sequenced, func(i, j int) bool {
+		return labelSequences[appendPointer(path, sequenced[i])] < labelSequences[appendPointer(path, sequenced[j])]
+	})
+	rank := func(k string) int {
+		for i, s := range specKeys {
+			if s == k {
+				return i
+			}
+		}
+		return len(specKeys)
+	}
+	sort.Slice(others, func(i, j int) bool {
+		ri, rj := rank(others[i]), rank(others[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return others[i] < others[j]
+	})
+
+	keys := make([]string, 0, len(obj))
+	for len(sequenced) > 0 && len(others) > 0 {
+		if labelSequences[appendPointer(path, sequenced[0])] <= len(keys)+1 {
+			keys, sequenced = append(keys, sequenced[0]), sequenced[1:]
+		} else {
+			keys, others = append(keys, others[0]), others[1:]
+		}
+	}
+	keys = append(keys, sequenced...)
+	return append(keys, others...)
+}
+
+// returns a copy of an exported spec with objects converted to yaml.MapSlice of ordered keys
+func orderSpec(node interface{}, path string) interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if v == nil {
+			return nil
+		}
+		keys := orderKeys(v, path)
+		result := make(yaml.MapSlice, 0, len(keys))
+		for _, k := range keys {
+			result = append(result, yaml.MapItem{Key: k, Value: orderSpec(v[k], childLabelPath(path, k))})
+		}
+		return result
+	case []interface{}:
+		if v == nil {
+			return nil
+		}
+		result := make([]interface{}, len(v))
+		for i, c := range v {
+			result[i] = orderSpec(c, appendPointer(path, strconv.Itoa(i)))
+		}
+		return result
+	case float64:
+		// keep integers as they are written in JSON, e.g., 1000000 instead of 1e+06 in YAML
+		if v == math.Trunc(v) && math.Abs(v) < 1e18 {
+			return int64(v)
+		}
+	}
+	return node
+}
+
+// write JSON of an ordered spec
+func writeOrderedJSON(buf *bytes.Buffer, node interface{}) error {
+	switch v := node.(type) {
+	case yaml.MapSlice:
+		buf.WriteByte('{')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeOrderedJSON(buf, fmt.Sprint(item.Key)); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeOrderedJSON(buf, item.Value); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, c := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeOrderedJSON(buf, c); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadKeyOrder(t *testing.T) {
+	order := readKeyOrder([]byte(`{"b": {"z": 1, "a": [{"y": 1, "x": 2}]}, "a~b": {"d/e": 1}}`))
+	assert.Equal(t, []string{"b", "a~b"}, order["#"], "JSON keys should be in source order")
+	assert.Equal(t, []string{"y", "x"}, order["#/b/a/0"], "keys of array items should be recorded")
+	assert.Equal(t, []string{"d/e"}, order["#/a~0b"], "pointer should be escaped")
+
+	order = readKeyOrder([]byte("b:\n  z: 1\n  a:\n  - w: 1\n    v: 2\n1: one\n"))
+	assert.Equal(t, []string{"b", "1"}, order["#"], "YAML keys should be in source order")
+	assert.Equal(t, []string{"w", "v"}, order["#/b/a/0"], "keys of array items should be recorded")
+}
+
+func TestExportKeyOrder(t *testing.T) {
+	startFakeTCMD(t)
+	root = "order-test"
+	dir, err := ioutil.TempDir("", "order")
+	assert.NoError(t, err, "temp dir should be created")
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	doc := `asyncapi: 2.0.0
+info:
+  title: Order test
+  version: 1.0.0
+servers:
+  production:
+    url: broker.example.com
+    protocol: mqtt
+channels:
+  zone/updated:
+    subscribe:
+      message:
+        $ref: '#/components/messages/zoneUpdated'
+  light/measured:
+    description: Light measurements.
+    publish:
+      message:
+        name: lightMeasured
+        payload:
+          type: object
+          properties:
+            sentAt:
+              type: string
+              format: date-time
+            lumens:
+              type: integer
+              minimum: 0
+components:
+  messages:
+    zoneUpdated:
+      payload:
+        type: string
+`
+	file := filepath.Join(dir, "order.yaml")
+	assert.NoError(t, ioutil.WriteFile(file, []byte(doc), 0644), "test file should be written")
+	spec, err := readSpec(file)
+	assert.NoError(t, err, "spec should be read")
+	assert.NoError(t, importAPISpec(spec), "import should not return error")
+
+	exported, err := exportAPISpec(root)
+	assert.NoError(t, err, "export should not return error")
+	assertSameJSON(t, spec, exported, "exported spec should be the same as the imported spec")
+
+	format = "yaml"
+	t.Cleanup(func() { format = "json" })
+	data, err := encode(exported)
+	assert.NoError(t, err, "spec should be encoded")
+	assert.Equal(t, doc, string(data), "YAML should keep the source order")
+
+	format = "json"
+	data, err = encode(exported)
+	assert.NoError(t, err, "spec should be encoded")
+	json := string(data)
+	for _, keys := range [][]string{
+		{`"asyncapi"`, `"info"`, `"servers"`, `"channels"`, `"components"`},
+		{`"zone/updated"`, `"light/measured"`},
+		{`"description"`, `"publish"`},
+		{`"name"`, `"payload"`},
+		{`"sentAt"`, `"lumens"`},
+	} {
+		for i := 1; i < len(keys); i++ {
+			assert.Less(t, strings.Index(json, keys[i-1]), strings.Index(json, keys[i]), "%s should be before %s", keys[i-1], keys[i])
+		}
+	}
+}
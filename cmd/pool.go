@@ -0,0 +1,72 @@
+package cmd
+
+/*
+Copyright © 2020 Yueming Xu <yxu@tibco.com>
+This file is subject to the license terms contained in the license file that is distributed with this file.
+*/
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// concurrency is the max number of spec nodes imported at the same time
+var concurrency = 1
+
+// workers holds a token for each goroutine that imports a spec node in addition to the main goroutine.
+// It is nil if concurrency is 1, so all spec nodes are imported sequentially.
+var workers chan struct{}
+
+// start a pool of concurrency - 1 workers for an import
+func startWorkers(n int) {
+	workers = nil
+	if n > 1 {
+		workers = make(chan struct{}, n-1)
+	}
+}
+
+// importGroup imports sibling spec nodes through the worker pool, and collects their errors.
+// Sibling nodes do not depend on each other once their parent asset is created.
+type importGroup struct {
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+	errs specErrors
+}
+
+// run imports a child node in a worker if one is idle, or in the calling goroutine otherwise,
+// so a parent waiting for its children never blocks the pool. tokens are names of the child relative to the parent.
+func (g *importGroup) run(f func() error, tokens ...string) {
+	select {
+	case workers <- struct{}{}:
+		g.wg.Add(1)
+		go func() {
+			defer func() {
+				if r := recover(); r != nil {
+					g.add(errors.Errorf("import panic: %v", r), tokens...)
+				}
+				<-workers
+				g.wg.Done()
+			}()
+			g.add(f(), tokens...)
+		}()
+	default:
+		g.add(f(), tokens...)
+	}
+}
+
+func (g *importGroup) add(err error, tokens ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.errs.add(err, tokens...)
+}
+
+// wait until all child nodes are imported, and return their errors sorted by JSON pointer, or nil if no error
+func (g *importGroup) wait() error {
+	g.wg.Wait()
+	sort.SliceStable(g.errs, func(i, j int) bool {
+		return g.errs[i].path < g.errs[j].path
+	})
+	return g.errs.result()
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportGroup(t *testing.T) {
+	startWorkers(4)
+	t.Cleanup(func() { startWorkers(1) })
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	var g importGroup
+	for i := 0; i < 20; i++ {
+		i := i
+		g.run(func() error {
+			mu.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mu.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+			if i%5 == 0 {
+				return errors.New("failed")
+			}
+			return nil
+		}, "n"+strconv.Itoa(i))
+	}
+	err := g.wait()
+	assert.LessOrEqual(t, maxRunning, 4, "running nodes should be bounded by concurrency")
+	assert.Greater(t, maxRunning, 1, "nodes should be imported concurrently")
+	if errs, ok := err.(specErrors); assert.True(t, ok, "errors should be collected") {
+		var paths []string
+		for _, e := range errs {
+			paths = append(paths, e.path)
+		}
+		assert.Equal(t, []string{"/n0", "/n10", "/n15", "/n5"}, paths, "errors should be sorted by JSON pointer")
+	}
+}
+
+func TestConcurrentImport(t *testing.T) {
+	fake := startFakeTCMD(t)
+	concurrency = 8
+	t.Cleanup(func() { concurrency = 1 })
+	file := "../test-data/payment-initiation-openapi-flattened.json"
+	root = rootName(file)
+
+	spec, err := readSpec(file)
+	assert.NoError(t, err, "spec should be read")
+	assert.NoError(t, importAPISpec(spec), "import should not return error")
+
+	names := make(map[string]bool)
+	for _, dt := range fake.dataTypes {
+		assert.False(t, names[dt.Name], "data type %s should not be created twice", dt.Name)
+		names[dt.Name] = true
+	}
+	changes, err := diffAPISpec(spec, root)
+	assert.NoError(t, err, "export should not return error")
+	assert.Empty(t, changes, "exported spec should be the same as the imported spec")
+}
@@ -0,0 +1,270 @@
+package cmd
+
+/*
+Copyright © 2020 Yueming Xu <yxu@tibco.com>
+This file is subject to the license terms contained in the license file that is distributed with this file.
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// externalDefinitions maps refs of definitions in external documents, e.g., file:///specs/schemas/light.yaml#/Light,
+// to the definitions.
+// It is set by readSpec, and the definitions are imported after the spec.
+var externalDefinitions map[string]interface{}
+
+// readSpec reads and decodes a spec file, and resolves its external $refs relative to the directory of the file.
+// External $refs are replaced by the file URL of their document and the JSON pointer in it,
+// so data types of the same definition are named the same by all specs.
+func readSpec(file string) (map[string]interface{}, error) {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid spec file %s", file)
+	}
+	r := &refResolver{
+		mainDoc:     abs,
+		docs:        make(map[string]interface{}),
+		definitions: make(map[string]interface{}),
+	}
+	doc, err := r.load(abs)
+	if err != nil {
+		return nil, err
+	}
+	spec, ok := doc.(map[string]interface{})
+	if !ok {
+		return nil, errors.Errorf("spec %s type %T is not a map", file, doc)
+	}
+	if err := r.resolveNode(spec, abs); err != nil {
+		return nil, err
+	}
+	externalDefinitions = r.definitions
+	sourceKeyOrder = r.keyOrder
+	return spec, nil
+}
+
+// refResolver loads external documents of $refs, and collects the definitions they refer to
+type refResolver struct {
+	mainDoc     string
+	docs        map[string]interface{}
+	definitions map[string]interface{}
+	// key order of objects in the main document
+	keyOrder map[string][]string
+	// refs being resolved, used to detect circular refs
+	stack []refFrame
+}
+
+type refFrame struct {
+	ref   string
+	alias bool
+}
+
+// load and decode a document of a specified absolute path
+func (r *refResolver) load(file string) (interface{}, error) {
+	if doc, ok := r.docs[file]; ok {
+		return doc, nil
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read %s", file)
+	}
+	var doc interface{}
+	if err := decode(data, &doc); err != nil {
+		return nil, errors.Wrapf(err, "Failed to decode %s", file)
+	}
+	r.docs[file] = doc
+	if file == r.mainDoc {
+		r.keyOrder = readKeyOrder(data)
+	}
+	return doc, nil
+}
+
+// replace $refs in a node of a document by their resolved refs
+func (r *refResolver) resolveNode(node interface{}, doc string) error {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if ref, ok := v["$ref"].(string); ok {
+			resolved, err := r.resolveRef(ref, doc)
+			if err != nil {
+				return err
+			}
+			v["$ref"] = resolved
+		}
+		for k, c := range v {
+			if k == "$ref" {
+				continue
+			}
+			if err := r.resolveNode(c, doc); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, c := range v {
+			if err := r.resolveNode(c, doc); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolve a $ref found in a document, collect its definition if it is external, and return the ref to be used by the spec
+func (r *refResolver) resolveRef(ref string, doc string) (string, error) {
+	file, pointer := ref, ""
+	if i := strings.Index(ref, "#"); i >= 0 {
+		file, pointer = ref[:i], ref[i+1:]
+	}
+
+	switch {
+	case len(file) == 0:
+		if doc == r.mainDoc {
+			// local ref of the spec
+			return ref, nil
+		}
+		file = doc
+	case strings.HasPrefix(file, "file://"):
+		file = filepath.FromSlash(strings.TrimPrefix(file, "file://"))
+	case strings.Contains(file, "://"):
+		fmt.Printf("remote $ref %s is not resolved\n", ref)
+		return ref, nil
+	default:
+		file = filepath.Join(filepath.Dir(doc), filepath.FromSlash(file))
+	}
+	if file == r.mainDoc {
+		// external document refers back to the spec
+		return "#" + pointer, nil
+	}
+
+	resolved := docName(file) + "#" + pointer
+	if _, ok := r.definitions[resolved]; ok {
+		return resolved, nil
+	}
+	for i, f := range r.stack {
+		if f.ref != resolved {
+			continue
+		}
+		// a recursive definition is fine, but a chain of refs that never reaches a definition is not
+		for _, a := range r.stack[i:] {
+			if !a.alias {
+				return resolved, nil
+			}
+		}
+		chain := make([]string, 0, len(r.stack)-i+1)
+		for _, a := range r.stack[i:] {
+			chain = append(chain, a.ref)
+		}
+		return "", errors.Errorf("circular $ref %s", strings.Join(append(chain, resolved), " -> "))
+	}
+
+	content, err := r.load(file)
+	if err != nil {
+		return "", err
+	}
+	target := getRef(content, "#"+pointer)
+	if len(strings.Trim(pointer, "/")) == 0 {
+		target = content
+	}
+	if target == nil {
+		return "", errors.Errorf("$ref %s is not found in %s", ref, file)
+	}
+
+	// copy the definition, so refs in it are resolved relative to its own document only once
+	data, err := json.Marshal(target)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to copy $ref %s", ref)
+	}
+	var definition interface{}
+	if err := json.Unmarshal(data, &definition); err != nil {
+		return "", errors.Wrapf(err, "Failed to copy $ref %s", ref)
+	}
+	dm, _ := definition.(map[string]interface{})
+	_, isRef := dm["$ref"]
+	r.stack = append(r.stack, refFrame{ref: resolved, alias: isRef && len(dm) == 1})
+	err = r.resolveNode(definition, file)
+	r.stack = r.stack[:len(r.stack)-1]
+	if err != nil {
+		return "", err
+	}
+	r.definitions[resolved] = definition
+	return resolved, nil
+}
+
+// returns name of a document, i.e., the file URL of its canonical absolute path, so a shared document is named the same
+// by all specs that refer to it, and different documents of the same relative path are never named the same
+func docName(file string) string {
+	if real, err := filepath.EvalSymlinks(file); err == nil {
+		file = real
+	}
+	name := filepath.ToSlash(filepath.Clean(file))
+	if !strings.HasPrefix(name, "/") {
+		// windows path, e.g., C:/specs/common.json
+		name = "/" + name
+	}
+	return "file://" + name
+}
+
+// returns true if a data type is named by an external ref, e.g., file:///specs/schemas/light.yaml#/Light,
+// but not by a JSON pointer scoped by a root asset, e.g., streetlights#/channels/lightMeasured
+func isExternalRef(dataType string) bool {
+	return strings.HasPrefix(dataType, "file://") && strings.Contains(dataType, "#")
+}
+
+// import external definitions under a root asset per document, e.g., file:///specs/schemas/light.yaml.
+// The documents may be shared by other specs, so their assets are always upserted.
+func importExternalDefinitions() error {
+	if len(externalDefinitions) == 0 {
+		return nil
+	}
+	defer func(u bool, t *assetTree) {
+		upsert, syncTree = u, t
+	}(upsert, syncTree)
+	upsert, syncTree = true, nil
+
+	docs := make(map[string]int)
+	for _, ref := range sortedKeys(externalDefinitions) {
+		i := strings.Index(ref, "#")
+		doc, pointer := ref[:i], ref[i+1:]
+		pid, ok := docs[doc]
+		if !ok {
+			asset := Asset{
+				Name:                    doc,
+				Label:                   doc,
+				AssetType:               AssetTypes["JSON Element"],
+				DataElementAutoAssigned: false,
+				IsDisabled:              false,
+			}
+			id, err := createAsset(asset)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to create asset of document %s", doc)
+			}
+			pid = id
+			docs[doc] = pid
+		}
+
+		tid, err := setRef(ref)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimPrefix(pointer, "/")
+		if len(name) == 0 {
+			name = path.Base(doc)
+		}
+		definition := externalDefinitions[ref]
+		if strings.HasPrefix(pointer, "/components/messages/") {
+			err = createMessageAsset(name, definition, tid, pid)
+		} else {
+			err = createSchemaAsset(name, definition, tid, pid, false)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "Failed to import external definition %s", ref)
+		}
+	}
+	return nil
+}
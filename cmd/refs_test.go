@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadSpecExternalRefs(t *testing.T) {
+	startFakeTCMD(t)
+	root = "multi-file-test"
+
+	dir, err := filepath.Abs("../test-data/multi-file")
+	assert.NoError(t, err, "test dir should be found")
+	light := docName(filepath.Join(dir, "schemas", "light.yaml"))
+	common := docName(filepath.Join(dir, "common.json"))
+
+	spec, err := readSpec("../test-data/multi-file/streetlights.yaml")
+	assert.NoError(t, err, "multi-file spec should be resolved")
+	assert.Equal(t, light+"#/Light", getString(spec, "#/components/messages/lightMeasured/payload/$ref"), "relative ref should be named by its document")
+	assert.Equal(t, common+"#/components/messages/Error", getString(spec, "#/channels/light~1error/subscribe/message/$ref"), "ref should be named by its document")
+
+	// refs in external documents are resolved relative to the document
+	assert.Equal(t, light+"#/Unit", getString(externalDefinitions[light+"#/Light"], "#/properties/unit/$ref"))
+	assert.Equal(t, light+"#/Unit", getString(externalDefinitions[common+"#/components/messages/Error"], "#/payload/properties/code/$ref"))
+	assert.Equal(t, "#/components/schemas/lightId", getString(externalDefinitions[common+"#/components/messages/Error"], "#/payload/properties/lightId/$ref"), "ref back to the spec should be local")
+	assert.Equal(t, light+"#/Zone", getString(externalDefinitions[light+"#/Zone"], "#/properties/subZones/items/$ref"), "recursive schema should be resolved")
+	assert.Len(t, externalDefinitions, 4, "referred definitions should be collected")
+
+	assert.NoError(t, importAPISpec(spec), "import should not return error")
+	doc, err := getRootAsset(light)
+	assert.NoError(t, err, "root asset should be queried")
+	assert.NotNil(t, doc, "external document should be imported as root asset")
+	assert.Greater(t, getAssetDataType(light+"#/Zone"), 0, "external definition should be registered as data type")
+
+	exported, err := exportAPISpec(root)
+	assert.NoError(t, err, "export should not return error")
+	assertSameJSON(t, spec, exported, "exported spec should keep external refs")
+}
+
+func TestReadSpecCircularRefs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "refs")
+	assert.NoError(t, err, "temp dir should be created")
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	files := map[string]string{
+		"spec.yaml": "asyncapi: 2.0.0\ncomponents:\n  schemas:\n    a:\n      $ref: 'a.yaml#/A'\n",
+		"a.yaml":    "A:\n  $ref: 'b.yaml#/B'\n",
+		"b.yaml":    "B:\n  $ref: 'file://" + filepath.ToSlash(filepath.Join(dir, "a.yaml")) + "#/A'\n",
+	}
+	for name, content := range files {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644), "test file should be written")
+	}
+	_, err = readSpec(filepath.Join(dir, "spec.yaml"))
+	if assert.Error(t, err, "circular refs should be rejected") {
+		a, b := docName(filepath.Join(dir, "a.yaml"))+"#/A", docName(filepath.Join(dir, "b.yaml"))+"#/B"
+		assert.Contains(t, err.Error(), "circular $ref "+a+" -> "+b+" -> "+a, "file URL should refer to the same document")
+	}
+
+	files["b.yaml"] = "B:\n  type: string\n"
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "b.yaml"), []byte(files["b.yaml"]), 0644), "test file should be written")
+	_, err = readSpec(filepath.Join(dir, "spec.yaml"))
+	assert.NoError(t, err, "chain of refs should be resolved")
+
+	files["spec.yaml"] = "asyncapi: 2.0.0\ncomponents:\n  schemas:\n    a:\n      $ref: 'a.yaml#/Missing'\n"
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "spec.yaml"), []byte(files["spec.yaml"]), 0644), "test file should be written")
+	_, err = readSpec(filepath.Join(dir, "spec.yaml"))
+	assert.Error(t, err, "missing ref should be rejected")
+}
+
+func TestExternalDocumentIdentity(t *testing.T) {
+	fake := startFakeTCMD(t)
+	dir, err := ioutil.TempDir("", "docs")
+	assert.NoError(t, err, "temp dir should be created")
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	// a/spec.yaml and b/spec.yaml refer to the same shared file, and to different files of the same relative path
+	files := map[string]string{
+		"a/spec.yaml":       "asyncapi: 2.0.0\ncomponents:\n  schemas:\n    light:\n      $ref: '../shared/light.yaml#/Light'\n    unit:\n      $ref: 'common.json#/Unit'\n",
+		"b/spec.yaml":       "asyncapi: 2.0.0\ncomponents:\n  schemas:\n    light:\n      $ref: '../shared/light.yaml#/Light'\n    unit:\n      $ref: 'common.json#/Unit'\n",
+		"a/common.json":     `{"Unit": {"type": "string"}}`,
+		"b/common.json":     `{"Unit": {"type": "integer"}}`,
+		"shared/light.yaml": "Light:\n  type: integer\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755), "test dir should be created")
+		assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644), "test file should be written")
+	}
+
+	var refs []string
+	for _, name := range []string{"a", "b"} {
+		root = name
+		spec, err := readSpec(filepath.Join(dir, name, "spec.yaml"))
+		if !assert.NoError(t, err, "spec should be read") {
+			return
+		}
+		assert.NoError(t, importAPISpec(spec), "import should not return error")
+		refs = append(refs, getString(spec, "#/components/schemas/light/$ref"), getString(spec, "#/components/schemas/unit/$ref"))
+	}
+	assert.Equal(t, refs[0], refs[2], "shared document should be named the same by specs in different folders")
+	assert.NotEqual(t, refs[1], refs[3], "documents of the same relative path should be named differently")
+
+	names := make(map[string]int)
+	for _, dt := range fake.dataTypes {
+		names[dt.Name]++
+	}
+	assert.Equal(t, 1, names[refs[0]], "data type of a shared definition should not be duplicated")
+	for _, name := range []string{"a", "b"} {
+		doc, err := getRootAsset(docName(filepath.Join(dir, name, "common.json")))
+		if assert.NoError(t, err, "root asset should be queried") && assert.NotNil(t, doc, "each document should have a root asset") {
+			unit, err := client.FindChildAsset(tcmdContext(), doc.ID, "Unit")
+			assert.NoError(t, err, "definition should be queried")
+			if assert.NotNil(t, unit, "definition should be imported under its document") {
+				assert.Contains(t, unit.Comment, map[string]string{"a": "string", "b": "integer"}[name], "definition of another document should not overwrite it")
+			}
+		}
+	}
+
+	assert.NoError(t, cleanExternalDefinitions(), "external definitions should be cleaned")
+	doc, err := getRootAsset(docName(filepath.Join(dir, "b", "common.json")))
+	assert.NoError(t, err, "root asset should be queried")
+	assert.Nil(t, doc, "root asset of external document should be deleted")
+	assert.Equal(t, 0, getAssetDataType(refs[3]), "data type of external definition should be deleted")
+	assert.Greater(t, getAssetDataType(refs[1]), 0, "definitions of other specs should be kept")
+}
+
+func TestIsExternalRef(t *testing.T) {
+	assert.True(t, isExternalRef(docName("/specs/schemas/light.yaml")+"#/Light"), "definition of external document should be external ref")
+	assert.False(t, isExternalRef("spec-a#/channels/lightMeasured"), "JSON pointer scoped by root should not be external ref")
+	assert.False(t, isExternalRef("#/components/schemas/Light"), "component should not be external ref")
+	assert.False(t, isExternalRef("string"), "primitive type should not be external ref")
+}
@@ -0,0 +1,102 @@
+package cmd
+
+/*
+Copyright © 2020 Yueming Xu <yxu@tibco.com>
+This file is subject to the license terms contained in the license file that is distributed with this file.
+*/
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// specError is an error of an imported spec node identified by its JSON pointer relative to the spec root
+type specError struct {
+	path string
+	err  error
+}
+
+func (e *specError) Error() string {
+	return fmt.Sprintf("#%s: %v", e.path, e.err)
+}
+
+// specErrors collects errors of spec nodes, so import can continue with sibling nodes after a node fails
+type specErrors []*specError
+
+func (e specErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, se := range e {
+		msgs[i] = se.Error()
+	}
+	return fmt.Sprintf("failed to import %d node(s): %s", len(e), strings.Join(msgs, "; "))
+}
+
+// add records an error of a child node, where tokens are names of the child relative to the current node
+func (e *specErrors) add(err error, tokens ...string) {
+	switch v := atNode(err, tokens...).(type) {
+	case *specError:
+		*e = append(*e, v)
+	case specErrors:
+		*e = append(*e, v...)
+	}
+}
+
+// result returns collected errors, or nil if no error is collected
+func (e specErrors) result() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// atNode prefixes JSON pointer tokens of a child node to the paths of its errors. It returns nil if err is nil.
+func atNode(err error, tokens ...string) error {
+	if err == nil {
+		return nil
+	}
+	prefix := strings.TrimPrefix(jsonPointer(tokens...), "#")
+	switch v := err.(type) {
+	case *specError:
+		return &specError{path: prefix + v.path, err: v.err}
+	case specErrors:
+		result := make(specErrors, len(v))
+		for i, se := range v {
+			result[i] = &specError{path: prefix + se.path, err: se.err}
+		}
+		return result
+	default:
+		return &specError{path: prefix, err: err}
+	}
+}
+
+// jsonPointer returns the JSON pointer of escaped tokens relative to the spec root,
+// e.g., channel name light/measured -> #/channels/light~1measured
+func jsonPointer(tokens ...string) string {
+	pointer := "#"
+	for _, t := range tokens {
+		pointer += "/" + strings.Replace(strings.Replace(t, "~", "~0", -1), "/", "~1", -1)
+	}
+	return pointer
+}
+
+// print import statistics and the JSON pointer of each failed spec node
+func printImportSummary(err error) {
+	status := "succeeded"
+	if err != nil {
+		status = "failed"
+	}
+	fmt.Printf("import summary of %s: %s, created %d, updated %d, unchanged %d\n",
+		root, status, stats.created, stats.updated, stats.unchanged)
+	if err == nil {
+		return
+	}
+	if errs, ok := errors.Cause(err).(specErrors); ok {
+		for _, se := range errs {
+			fmt.Println("  failed", se)
+		}
+		return
+	}
+	fmt.Println("  failed", err)
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpecErrorPaths(t *testing.T) {
+	var channel specErrors
+	channel.add(errors.New("bad request"), "subscribe", "message")
+	channel.add(nil, "publish")
+	channel.add(errors.New("not a map"), "parameters", "streetlightId")
+
+	var errs specErrors
+	errs.add(channel.result(), "channels", "light/measured")
+	errs.add(nil, "info")
+	errs.add(errors.New("timeout"), "components", "schemas", "a~b")
+
+	assert.Equal(t, 3, len(errs), "number of failed nodes does not match")
+	assert.Equal(t, "#/channels/light~1measured/subscribe/message: bad request", errs[0].Error())
+	assert.Equal(t, "#/channels/light~1measured/parameters/streetlightId: not a map", errs[1].Error())
+	assert.Equal(t, "#/components/schemas/a~0b: timeout", errs[2].Error())
+
+	var root specErrors
+	root.add(errors.New("conflict"))
+	assert.Equal(t, "#: conflict", root[0].Error())
+
+	var none specErrors
+	assert.NoError(t, none.result(), "result should be nil if no error is collected")
+	assert.Nil(t, atNode(nil, "info"), "atNode should return nil for nil error")
+}
@@ -5,13 +5,18 @@ Copyright © 2020 Yueming Xu <yxu@tibco.com>
 This file is subject to the license terms contained in the license file that is distributed with this file.
 */
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/yxuco/tcmdtool/tcmd"
 
 	homedir "github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
 	"github.com/spf13/viper"
 )
 
@@ -21,20 +26,44 @@ var (
 	user      string
 	password  string
 	authtoken string
+	dryRun    bool
+	recordDir string
+	replayDir string
+	timeout   time.Duration
+	retries   int
 )
 
 var (
 	// TCDataspace TCMD dataspace name
-	TCDataspace = "Tabula"
+	TCDataspace = tcmd.DefaultDataspace
 	// TCDataset TCMD dataset name
-	TCDataset = "Tabula"
+	TCDataset = tcmd.DefaultDataset
 )
 
+// client of TCMD REST API, initialized from config file and command-line flags
+var client *tcmd.Client
+
+// recorder intercepts TCMD updates in dry-run mode
+var recorder *tcmd.Recorder
+
+// cassette records or replays TCMD requests and responses
+var cassette *tcmd.Cassette
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "tcmdtool",
 	Short: "Utility CLI for TIBCO Cloud Metadata",
 	Long:  `Utility CLI for TIBCO Cloud Metadata`,
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		if recorder != nil {
+			recorder.WritePlan(os.Stdout)
+		}
+		if replayDir != "" && cassette != nil {
+			if n := cassette.Unused(); n > 0 {
+				fmt.Printf("%d recorded request(s) in %s are not replayed\n", n, replayDir)
+			}
+		}
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -53,6 +82,11 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&url, "url", "", "TCMD REST API URL, e.g., https://metadata.cloud.tibco.com/s/ienmnadebipc/ebx-ca-tabula/rest/v1")
 	rootCmd.PersistentFlags().StringVarP(&user, "user", "u", "", "TCMD technical user to invoke REST API")
 	rootCmd.PersistentFlags().StringVarP(&password, "password", "p", "", "Password of TCMD technical user to invoke REST API")
+	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "print planned TCMD changes without creating, updating or deleting anything")
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", tcmd.DefaultTimeout, "timeout of a single TCMD REST call")
+	rootCmd.PersistentFlags().IntVar(&retries, "retries", tcmd.DefaultRetryPolicy.MaxRetries, "number of retries of a TCMD REST call that fails with a network error, or status 429 or 5xx")
+	rootCmd.PersistentFlags().StringVar(&recordDir, "record", "", "directory to save every TCMD request and response")
+	rootCmd.PersistentFlags().StringVar(&replayDir, "replay", "", "directory of recorded TCMD responses to replay instead of calling TCMD")
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -113,33 +147,61 @@ func initConfig() {
 		authtoken = base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", user, password)))
 		fmt.Println("Basic auth token", authtoken)
 	}
+
+	opts := []tcmd.Option{
+		tcmd.WithAuthToken(authtoken),
+		tcmd.WithDataset(TCDataspace, TCDataset),
+		tcmd.WithTimeout(timeout),
+		tcmd.WithRetry(retryPolicy()),
+		tcmd.WithLogger(func(format string, args ...interface{}) {
+			fmt.Printf(format, args...)
+		}),
+	}
+	var transport http.RoundTripper
+	if recordDir != "" || replayDir != "" {
+		var err error
+		switch {
+		case recordDir != "" && replayDir != "":
+			err = errors.New("--record and --replay cannot be used together")
+		case recordDir != "":
+			// save TCMD requests and responses, e.g., to reproduce a failed import
+			cassette, err = tcmd.RecordCassette(recordDir, url, nil)
+		default:
+			cassette, err = tcmd.ReplayCassette(replayDir, url)
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		transport = cassette
+	}
+	if dryRun {
+		// send only GET requests to TCMD, and record other requests
+		recorder = tcmd.NewRecorder(transport)
+		transport = recorder
+	}
+	if transport != nil {
+		opts = append(opts, tcmd.WithHTTPClient(&http.Client{Transport: transport}))
+	}
+	client = tcmd.NewClient(url, opts...)
 }
 
-// Asset difines asset in TCMD
-type Asset struct {
-	ID            int    `json:"id,omitempty"`
-	Name          string `json:"name"`
-	Label         string `json:"label"`
-	Description   string `json:"description,omitempty"`
-	AssetType     string `json:"assetType"`
-	AssetDataType string `json:"assetDataType,omitempty"`
-	Logo          struct {
-		Attachment string `json:"attachment"`
-	} `json:"logo,omitempty"`
-	Comment                 string `json:"comment,omitempty"`
-	Parent                  string `json:"parent,omitempty"`
-	Instance                string `json:"instance,omitempty"`
-	DataElementAutoAssigned bool   `json:"dataElementAutoAssigned"`
-	IsDisabled              bool   `json:"isDisabled"`
-	Version                 string `json:"version,omitempty"`
+// retry policy of TCMD REST calls with the number of retries specified in command-line
+func retryPolicy() tcmd.RetryPolicy {
+	policy := tcmd.DefaultRetryPolicy
+	if retries >= 0 {
+		policy.MaxRetries = retries
+	}
+	return policy
 }
 
-// DataType defines asset data type in TCMD
-type DataType struct {
-	ID          int    `json:"id,omitempty"`
-	Name        string `json:"name"`
-	Label       string `json:"label"`
-	Description string `json:"description,omitempty"`
-	BuiltIn     bool   `json:"builtIn"`
-	ComplexType bool   `json:"complexType"`
+// Asset is an alias of TCMD asset defined in package tcmd
+type Asset = tcmd.Asset
+
+// DataType is an alias of TCMD asset data type defined in package tcmd
+type DataType = tcmd.DataType
+
+// context used for TCMD REST calls
+func tcmdContext() context.Context {
+	return context.Background()
 }
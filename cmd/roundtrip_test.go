@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRoundTrip imports each spec in test-data into a fake TCMD, and checks that the exported spec is the same
+func TestRoundTrip(t *testing.T) {
+	files, err := findSpecFiles("../test-data")
+	assert.NoError(t, err, "test specs should be found")
+
+	var specs []string
+	for _, f := range files {
+		if isAPISpecFile(f) {
+			specs = append(specs, f)
+		}
+	}
+	assert.NotEmpty(t, specs, "test-data should contain API specs")
+
+	for _, file := range specs {
+		file := file
+		t.Run(filepath.ToSlash(file), func(t *testing.T) {
+			startFakeTCMD(t)
+			root = rootName(file)
+
+			spec, err := readSpec(file)
+			if !assert.NoError(t, err, "spec should be read") {
+				return
+			}
+			if !assert.NoError(t, importAPISpec(spec), "import should not return error") {
+				return
+			}
+			changes, err := diffAPISpec(spec, root)
+			assert.NoError(t, err, "export should not return error")
+			for _, c := range changes {
+				t.Errorf("%s %s", c.Op, c.Path)
+			}
+		})
+	}
+}
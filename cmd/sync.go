@@ -0,0 +1,304 @@
+package cmd
+
+/*
+Copyright © 2020 Yueming Xu <yxu@tibco.com>
+This file is subject to the license terms contained in the license file that is distributed with this file.
+
+Test command: ./tcmdtool sync -i test-data/streetlights.yml
+*/
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// syncCmd represents the sync command
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Synchronize TCMD assets with an API spec",
+	Long: `Synchronize TCMD assets with an API spec.
+Assets are created, updated or deleted, so the asset tree of the root matches the spec file exactly.
+Data types of definitions that are no longer in the spec are deleted as well,
+except component data types that are still used by other specs.
+Created and updated assets are rolled back if the import fails, and assets are deleted only after the import
+succeeds, but the deletions are not rolled back.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("sync", input)
+		spec, err := readSpec(input)
+		if err != nil {
+			panic(err)
+		}
+		// set root asset name as input file name if it is not specified
+		if root == "" {
+			root = rootName(input)
+		}
+		if err := syncAPISpec(spec); err != nil {
+			panic(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+
+	syncCmd.Flags().StringVarP(&input, "input", "i", "", "name of the file to be synchronized")
+	syncCmd.Flags().StringVarP(&root, "root", "r", "", "name of root asset created from input file")
+	syncCmd.Flags().IntVar(&concurrency, "concurrency", 1, "max number of sibling spec nodes imported concurrently, e.g., 8")
+	syncCmd.MarkFlagRequired("input")
+}
+
+// assetTree caches existing assets under a root asset, so they can be matched by parent and name
+type assetTree struct {
+	// mu guards matching by concurrent imports
+	mu       sync.Mutex
+	assets   map[int]*Asset
+	children map[int][]int
+	// unmatched assets keyed by parent ID and name
+	unmatched map[string][]int
+	touched   map[int]bool
+	// data types of deleted definitions keyed by name
+	deletedTypes map[string]int
+}
+
+// syncTree is set when importing in sync mode
+var syncTree *assetTree
+
+// importStats counts assets processed by import in upsert or sync mode
+type importStats struct {
+	created   int
+	updated   int
+	unchanged int
+	deleted   int
+	// paths of top assets of deleted sub-trees
+	deletedPaths []string
+}
+
+var (
+	stats importStats
+	// statsLock guards counters of stats incremented by concurrent imports
+	statsLock sync.Mutex
+)
+
+// increment a counter of stats
+func count(counter *int) {
+	statsLock.Lock()
+	defer statsLock.Unlock()
+	*counter++
+}
+
+func assetKey(parent int, name string) string {
+	return fmt.Sprintf("%d/%s", parent, name)
+}
+
+func assetParentID(asset *Asset) int {
+	if pid, err := strconv.Atoi(asset.Parent); err == nil {
+		return pid
+	}
+	return 0
+}
+
+// fetch the root asset and all its descendants recursively
+func loadAssetTree(rid int) (*assetTree, error) {
+	tree := &assetTree{
+		assets:       make(map[int]*Asset),
+		children:     make(map[int][]int),
+		unmatched:    make(map[string][]int),
+		touched:      make(map[int]bool),
+		deletedTypes: make(map[string]int),
+	}
+	if rid == 0 {
+		return tree, nil
+	}
+	asset, err := getAssetByID(rid)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to fetch root asset %d", rid)
+	}
+	// root asset is matched by name without parent
+	asset.Parent = ""
+	tree.add(asset)
+	if err := tree.loadChildren(rid); err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+func (t *assetTree) add(asset *Asset) {
+	t.assets[asset.ID] = asset
+	key := assetKey(assetParentID(asset), asset.Name)
+	t.unmatched[key] = append(t.unmatched[key], asset.ID)
+}
+
+func (t *assetTree) loadChildren(id int) error {
+	children, err := getChildrenAsset(id)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to fetch children of asset %d", id)
+	}
+	for i := range children {
+		c := &children[i]
+		t.add(c)
+		t.children[id] = append(t.children[id], c.ID)
+		if err := t.loadChildren(c.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// returns an unmatched asset of the specified parent and name, and mark it as touched
+func (t *assetTree) match(parent int, name string) *Asset {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := assetKey(parent, name)
+	ids := t.unmatched[key]
+	if len(ids) == 0 {
+		return nil
+	}
+	t.unmatched[key] = ids[1:]
+	t.touched[ids[0]] = true
+	return t.assets[ids[0]]
+}
+
+// returns JSON pointer like path of an asset in the tree
+func (t *assetTree) path(id int) string {
+	var names []string
+	for a, ok := t.assets[id]; ok; a, ok = t.assets[assetParentID(a)] {
+		names = append([]string{a.Name}, names...)
+		if a.Parent == "" {
+			break
+		}
+	}
+	return "/" + strings.Join(names, "/")
+}
+
+// returns names of an asset and its ancestors below the root asset, i.e., tokens of its spec path
+func (t *assetTree) tokens(id int) []string {
+	var names []string
+	for a, ok := t.assets[id]; ok && a.Parent != ""; a, ok = t.assets[assetParentID(a)] {
+		names = append([]string{a.Name}, names...)
+	}
+	return names
+}
+
+// returns name of the data type registered for a definition of a spec path, i.e., a component,
+// or an AsyncAPI 3.0 channel, channel message or operation, or "" if the path is not a definition
+func definitionTypeName(tokens []string) string {
+	n := len(tokens)
+	switch {
+	case n == 3 && tokens[0] == "components",
+		n == 5 && tokens[0] == "components" && tokens[1] == "channels" && tokens[3] == "messages",
+		n == 2 && (tokens[0] == "channels" || tokens[0] == "operations"),
+		n == 4 && tokens[0] == "channels" && tokens[2] == "messages":
+		return scopedTypeName(jsonPointer(tokens...))
+	}
+	return ""
+}
+
+// record data type of a deleted definition asset, if the asset is typed by it
+func (t *assetTree) addDeletedType(id int) {
+	asset := t.assets[id]
+	name := definitionTypeName(t.tokens(id))
+	if name == "" || asset.AssetDataType == "" {
+		return
+	}
+	if tid := getAssetDataType(name); tid > 0 && strconv.Itoa(tid) == asset.AssetDataType {
+		t.deletedTypes[name] = tid
+	}
+}
+
+// delete data types of deleted definitions after their assets are deleted, except component data types used by other specs
+func (t *assetTree) deleteDefinitionDataTypes() error {
+	names := make([]string, 0, len(t.deletedTypes))
+	for name := range t.deletedTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		tid := t.deletedTypes[name]
+		inUse, err := sharedDataTypeInUse(tid, name)
+		if err != nil {
+			return err
+		}
+		if inUse {
+			continue
+		}
+		fmt.Printf("delete data type %d -> %s\n", tid, name)
+		if err := deleteAssetDataType(tid); err != nil {
+			return errors.Wrapf(err, "Failed to delete data type %s", name)
+		}
+	}
+	return nil
+}
+
+// delete assets that are not touched by the import, children before parents,
+// and data types of the definitions that are no longer in the spec
+func (t *assetTree) deleteUntouched(id int, parentTouched bool) error {
+	touched := t.touched[id]
+	for _, c := range t.children[id] {
+		if err := t.deleteUntouched(c, touched); err != nil {
+			return err
+		}
+	}
+	if touched {
+		return nil
+	}
+	path := t.path(id)
+	fmt.Printf("delete asset %d -> %s\n", id, path)
+	if err := deleteAsset(id); err != nil {
+		return errors.Wrapf(err, "Failed to delete asset %s", path)
+	}
+	t.addDeletedType(id)
+	stats.deleted++
+	if parentTouched {
+		// report only the top of a deleted sub-tree
+		stats.deletedPaths = append(stats.deletedPaths, path)
+	}
+	return nil
+}
+
+// import spec in upsert mode using cached asset tree, and then delete assets that are not in the spec.
+// The import is rolled back if it fails, and assets are deleted only after it succeeds,
+// but the deletions cannot be rolled back.
+func syncAPISpec(spec map[string]interface{}) error {
+	rid := 0
+	existing, err := getRootAsset(root)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to find root asset %s", root)
+	}
+	if existing != nil {
+		rid = existing.ID
+	}
+	if syncTree, err = loadAssetTree(rid); err != nil {
+		return err
+	}
+	upsert = true
+
+	if err := importWithRollback(spec); err != nil {
+		return err
+	}
+
+	if rid > 0 {
+		if err := syncTree.deleteUntouched(rid, true); err != nil {
+			return err
+		}
+		if err := syncTree.deleteDefinitionDataTypes(); err != nil {
+			return err
+		}
+	}
+	printSyncSummary()
+	return nil
+}
+
+func printSyncSummary() {
+	fmt.Printf("sync summary of %s: created %d, updated %d, deleted %d, unchanged %d\n",
+		root, stats.created, stats.updated, stats.deleted, stats.unchanged)
+	sort.Strings(stats.deletedPaths)
+	for _, p := range stats.deletedPaths {
+		fmt.Println("  deleted", p)
+	}
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncDeletesDataTypes(t *testing.T) {
+	fake := startFakeTCMD(t)
+	t.Cleanup(func() {
+		upsert = false
+		syncTree = nil
+	})
+	root = "sync-test"
+
+	doc := `{
+        "asyncapi": "3.0.0",
+        "info": {"title": "Sync test", "version": "1.0.0"},
+        "channels": {
+            "lightMeasured": {
+                "address": "light/measured",
+                "messages": {"lightMeasured": {"payload": {"$ref": "#/components/schemas/light~1measured"}}}
+            },
+            "lightDimmed": {
+                "address": "light/dimmed",
+                "messages": {"lightDimmed": {"payload": {"type": "string"}}}
+            }
+        },
+        "operations": {
+            "receiveLightMeasurement": {
+                "action": "receive",
+                "channel": {"$ref": "#/channels/lightMeasured"}
+            },
+            "receiveLightDimmed": {
+                "action": "receive",
+                "channel": {"$ref": "#/channels/lightDimmed"}
+            }
+        },
+        "components": {
+            "messages": {
+                "lightMeasured": {"payload": {"$ref": "#/components/schemas/light~1measured"}},
+                "lightDimmed": {"payload": {"type": "string"}}
+            },
+            "schemas": {
+                "light/measured": {"type": "integer"}
+            }
+        }
+    }`
+	var spec map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(doc), &spec), "test spec should be valid JSON")
+	assert.NoError(t, importAPISpec(spec), "import should not return error")
+
+	delete(getRef(spec, "#/channels").(map[string]interface{}), "lightDimmed")
+	delete(getRef(spec, "#/operations").(map[string]interface{}), "receiveLightDimmed")
+	delete(getRef(spec, "#/components/messages").(map[string]interface{}), "lightDimmed")
+	assert.NoError(t, syncAPISpec(spec), "sync should not return error")
+
+	names := make(map[string]bool)
+	for _, dt := range fake.dataTypes {
+		names[dt.Name] = true
+	}
+	for _, name := range []string{
+		"#/components/messages/lightMeasured",
+		"#/components/schemas/light~1measured",
+		root + "#/channels/lightMeasured",
+		root + "#/channels/lightMeasured/messages/lightMeasured",
+		root + "#/operations/receiveLightMeasurement",
+	} {
+		assert.True(t, names[name], "data type %s should be kept", name)
+	}
+	for _, name := range []string{
+		"#/components/messages/lightDimmed",
+		root + "#/channels/lightDimmed",
+		root + "#/channels/lightDimmed/messages/lightDimmed",
+		root + "#/operations/receiveLightDimmed",
+	} {
+		assert.False(t, names[name], "data type %s of removed definition should be deleted", name)
+	}
+
+	exported, err := exportAPISpec(root)
+	assert.NoError(t, err, "export should not return error")
+	assertSameJSON(t, spec, exported, "exported spec should be the same as the synced spec")
+}
+
+func TestSyncKeepsSharedDataTypes(t *testing.T) {
+	fake := startFakeTCMD(t)
+	t.Cleanup(func() {
+		upsert = false
+		syncTree = nil
+	})
+
+	doc := `{
+        "asyncapi": "2.0.0",
+        "info": {"title": "Shared test", "version": "1.0.0"},
+        "channels": {},
+        "components": {
+            "schemas": {
+                "Light": {"type": "integer"},
+                "Shared": {"type": "string"}
+            }
+        }
+    }`
+	specs := make(map[string]map[string]interface{})
+	for _, name := range []string{"spec-a", "spec-b"} {
+		var spec map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(doc), &spec), "test spec should be valid JSON")
+		root = name
+		assert.NoError(t, importAPISpec(spec), "import of %s should not return error", name)
+		specs[name] = spec
+	}
+
+	root = "spec-a"
+	delete(getRef(specs[root], "#/components/schemas").(map[string]interface{}), "Shared")
+	assert.NoError(t, syncAPISpec(specs[root]), "sync should not return error")
+
+	names := make(map[string]bool)
+	for _, dt := range fake.dataTypes {
+		names[dt.Name] = true
+	}
+	assert.True(t, names["#/components/schemas/Shared"], "data type used by another spec should be kept")
+
+	exported, err := exportAPISpec("spec-b")
+	assert.NoError(t, err, "export should not return error")
+	assertSameJSON(t, specs["spec-b"], exported, "other spec should not be changed by sync")
+}
+
+func TestSyncRollback(t *testing.T) {
+	fake := startFakeTCMD(t)
+	t.Cleanup(func() {
+		upsert = false
+		syncTree = nil
+	})
+	root = "rollback-test"
+
+	doc := `{
+        "asyncapi": "2.0.0",
+        "info": {"title": "Rollback test", "version": "1.0.0"},
+        "channels": {
+            "light/measured": {"description": "old description"},
+            "light/dimmed": {}
+        }
+    }`
+	var spec map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(doc), &spec), "test spec should be valid JSON")
+	assert.NoError(t, importAPISpec(spec), "import should not return error")
+	count := len(fake.assets)
+
+	var changed map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(doc), &changed), "test spec should be valid JSON")
+	channels := getRef(changed, "#/channels").(map[string]interface{})
+	channels["light/measured"] = map[string]interface{}{"description": "new description"}
+	channels["light/on"] = map[string]interface{}{}
+	delete(channels, "light/dimmed")
+	changed["servers"] = "invalid"
+	assert.Error(t, syncAPISpec(changed), "sync should return error of invalid servers")
+
+	assert.Equal(t, count, len(fake.assets), "created assets should be rolled back, and no asset should be deleted")
+	exported, err := exportAPISpec(root)
+	assert.NoError(t, err, "export should not return error")
+	assertSameJSON(t, spec, exported, "updated assets should be restored")
+}
@@ -10,4 +10,5 @@ require (
 	github.com/spf13/viper v1.7.0
 	github.com/stretchr/testify v1.6.1
 	golang.org/x/sys v0.0.0-20191010194322-b09406accb47 // indirect
+	gopkg.in/yaml.v2 v2.2.4
 )
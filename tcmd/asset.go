@@ -0,0 +1,292 @@
+package tcmd
+
+/*
+Copyright © 2020 Yueming Xu <yxu@tibco.com>
+This file is subject to the license terms contained in the license file that is distributed with this file.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Asset difines asset in TCMD
+type Asset struct {
+	ID            int    `json:"id,omitempty"`
+	Name          string `json:"name"`
+	Label         string `json:"label"`
+	Description   string `json:"description,omitempty"`
+	AssetType     string `json:"assetType"`
+	AssetDataType string `json:"assetDataType,omitempty"`
+	Logo          struct {
+		Attachment string `json:"attachment"`
+	} `json:"logo,omitempty"`
+	Comment                 string `json:"comment,omitempty"`
+	Parent                  string `json:"parent,omitempty"`
+	Instance                string `json:"instance,omitempty"`
+	DataElementAutoAssigned bool   `json:"dataElementAutoAssigned"`
+	IsDisabled              bool   `json:"isDisabled"`
+	Version                 string `json:"version,omitempty"`
+	// Sequence is the position of the asset among its siblings in the source spec, starting from 1
+	Sequence int `json:"sequence,omitempty"`
+}
+
+// DataType defines asset data type in TCMD
+type DataType struct {
+	ID          int    `json:"id,omitempty"`
+	Name        string `json:"name"`
+	Label       string `json:"label"`
+	Description string `json:"description,omitempty"`
+	BuiltIn     bool   `json:"builtIn"`
+	ComplexType bool   `json:"complexType"`
+}
+
+// GetAsset fetches asset of a specified ID
+func (c *Client) GetAsset(ctx context.Context, id int) (*Asset, error) {
+	resp, err := c.Get(ctx, fmt.Sprintf("asset/%d", id), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed TCMD request")
+	}
+
+	var result Asset
+	if err = json.Unmarshal(resp, &result); err != nil {
+		return nil, errors.Wrap(err, "Failed to unmarshal TCMD response")
+	}
+	return &result, nil
+}
+
+// FindAssetByName returns the first asset of a specified name, or nil if it does not exist
+func (c *Client) FindAssetByName(ctx context.Context, name string) (*Asset, error) {
+	result, err := c.queryAssets(ctx, "name="+quote(name))
+	if err != nil {
+		return nil, err
+	}
+	if len(result) > 0 {
+		return &result[0], nil
+	}
+	return nil, nil
+}
+
+// FindChildAsset returns the asset of a specified name under a parent, or nil if it does not exist.
+// It looks for a root asset without parent if parent is 0.
+func (c *Client) FindChildAsset(ctx context.Context, parent int, name string) (*Asset, error) {
+	result, err := c.findChildAssets(ctx, parent, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) > 0 {
+		return &result[0], nil
+	}
+	return nil, nil
+}
+
+// returns assets of a specified name under a parent, or root assets of the name if parent is 0
+func (c *Client) findChildAssets(ctx context.Context, parent int, name string) ([]Asset, error) {
+	if parent > 0 {
+		return c.queryAssets(ctx, fmt.Sprintf("parent='%d' and name=%s", parent, quote(name)))
+	}
+
+	result, err := c.queryAssets(ctx, "name="+quote(name))
+	if err != nil {
+		return nil, err
+	}
+	var roots []Asset
+	for _, a := range result {
+		if a.Parent == "" {
+			roots = append(roots, a)
+		}
+	}
+	return roots, nil
+}
+
+// ListChildren returns children assets of a specified parent
+func (c *Client) ListChildren(ctx context.Context, parent int) ([]Asset, error) {
+	result, err := c.queryAssets(ctx, fmt.Sprintf("parent='%d'", parent))
+	if err != nil {
+		return nil, err
+	}
+	if len(result) > 0 {
+		return result, nil
+	}
+	return nil, nil
+}
+
+// quote returns a string literal of a predicate value, where a single quote is escaped by doubling it
+func quote(value string) string {
+	return "'" + strings.Replace(value, "'", "''", -1) + "'"
+}
+
+// ListAssetsByDataType returns assets typed by a specified data type
+func (c *Client) ListAssetsByDataType(ctx context.Context, dataType int) ([]Asset, error) {
+	return c.queryAssets(ctx, fmt.Sprintf("assetDataType='%d'", dataType))
+}
+
+func (c *Client) queryAssets(ctx context.Context, predicate string) ([]Asset, error) {
+	params := map[string]string{
+		"predicate": predicate,
+	}
+	resp, err := c.Get(ctx, "asset", params)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed TCMD request")
+	}
+
+	var result []Asset
+	if err = json.Unmarshal(resp, &result); err != nil {
+		return nil, errors.Wrap(err, "Failed to unmarshal TCMD response")
+	}
+	return result, nil
+}
+
+// CreateAsset creates a new asset, and returns the created asset with its new ID.
+// If the request is retried, an asset of the same content under the same parent created by a failed attempt is returned.
+// Assets of the same name under the parent are queried before the request, so an existing asset is never returned.
+func (c *Client) CreateAsset(ctx context.Context, asset Asset) (*Asset, error) {
+	parent, perr := 0, error(nil)
+	if len(asset.Parent) > 0 {
+		parent, perr = strconv.Atoi(asset.Parent)
+	}
+	// assets of the same name and parent that exist before the request are never returned as created by it
+	before := make(map[int]bool)
+	if c.retry.MaxRetries > 0 && perr == nil {
+		siblings, err := c.findChildAssets(ctx, parent, asset.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range siblings {
+			before[a.ID] = true
+		}
+	}
+	resp, err := c.post(ctx, "asset", asset, func(ctx context.Context) ([]byte, error) {
+		if perr != nil {
+			return nil, nil
+		}
+		siblings, err := c.findChildAssets(ctx, parent, asset.Name)
+		if err != nil {
+			return nil, err
+		}
+		for i := range siblings {
+			if !before[siblings[i].ID] && sameAsset(&siblings[i], &asset) {
+				return json.Marshal(siblings[i])
+			}
+		}
+		return nil, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var result Asset
+	if err = json.Unmarshal(resp, &result); err != nil {
+		return nil, errors.Wrap(err, "Failed to unmarshal TCMD response")
+	}
+	if result.ID <= 0 {
+		return nil, errors.Errorf("TCMD did not return ID of created asset %s", asset.Name)
+	}
+	return &result, nil
+}
+
+// UpdateAsset replaces content of an existing asset of the same ID, and returns the updated asset
+func (c *Client) UpdateAsset(ctx context.Context, asset Asset) (*Asset, error) {
+	if asset.ID <= 0 {
+		return nil, errors.Errorf("Cannot update asset %s without ID", asset.Name)
+	}
+	resp, err := c.Put(ctx, fmt.Sprintf("asset/%d", asset.ID), asset)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp) == 0 {
+		// server may not return the updated asset
+		return &asset, nil
+	}
+	var result Asset
+	if err = json.Unmarshal(resp, &result); err != nil {
+		return nil, errors.Wrap(err, "Failed to unmarshal TCMD response")
+	}
+	return &result, nil
+}
+
+// returns true if an existing asset has the same content as a new asset
+func sameAsset(existing, asset *Asset) bool {
+	return existing.Label == asset.Label &&
+		existing.Description == asset.Description &&
+		existing.Comment == asset.Comment &&
+		existing.AssetType == asset.AssetType &&
+		existing.AssetDataType == asset.AssetDataType
+}
+
+// DeleteAsset deletes asset of a specified ID
+func (c *Client) DeleteAsset(ctx context.Context, id int) error {
+	_, err := c.Delete(ctx, fmt.Sprintf("asset/%d", id))
+	return err
+}
+
+func (c *Client) dataTypePath() string {
+	return fmt.Sprintf("%s/%s/datatype", c.dataspace, c.dataset)
+}
+
+// GetDataType fetches asset data type of a specified ID
+func (c *Client) GetDataType(ctx context.Context, id int) (*DataType, error) {
+	resp, err := c.Get(ctx, fmt.Sprintf("%s/%d", c.dataTypePath(), id), nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed TCMD request")
+	}
+
+	var result DataType
+	if err = json.Unmarshal(resp, &result); err != nil {
+		return nil, errors.Wrap(err, "Failed to unmarshal TCMD response")
+	}
+	return &result, nil
+}
+
+// FindDataTypeByName returns asset data type of a specified name, or nil if it does not exist
+func (c *Client) FindDataTypeByName(ctx context.Context, name string) (*DataType, error) {
+	params := map[string]string{
+		"predicate": "name=" + quote(name),
+	}
+	resp, err := c.Get(ctx, c.dataTypePath(), params)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed TCMD request")
+	}
+
+	var result []DataType
+	if err = json.Unmarshal(resp, &result); err != nil {
+		return nil, errors.Wrap(err, "Failed to unmarshal TCMD response")
+	}
+	if len(result) > 0 {
+		return &result[0], nil
+	}
+	return nil, nil
+}
+
+// CreateDataType creates a new asset data type, and returns the created data type with its new ID.
+// If the request is retried, a data type of the same name created by a failed attempt is returned.
+func (c *Client) CreateDataType(ctx context.Context, dataType DataType) (*DataType, error) {
+	resp, err := c.post(ctx, c.dataTypePath(), dataType, func(ctx context.Context) ([]byte, error) {
+		existing, err := c.FindDataTypeByName(ctx, dataType.Name)
+		if err != nil || existing == nil {
+			return nil, err
+		}
+		return json.Marshal(existing)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var result DataType
+	if err = json.Unmarshal(resp, &result); err != nil {
+		return nil, errors.Wrap(err, "Failed to unmarshal TCMD response")
+	}
+	if result.ID <= 0 {
+		return nil, errors.Errorf("TCMD did not return ID of created data type %s", dataType.Name)
+	}
+	return &result, nil
+}
+
+// DeleteDataType deletes asset data type of a specified ID
+func (c *Client) DeleteDataType(ctx context.Context, id int) error {
+	_, err := c.Delete(ctx, fmt.Sprintf("%s/%d", c.dataTypePath(), id))
+	return err
+}
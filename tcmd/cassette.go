@@ -0,0 +1,227 @@
+package tcmd
+
+/*
+Copyright © 2020 Yueming Xu <yxu@tibco.com>
+This file is subject to the license terms contained in the license file that is distributed with this file.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Cassette is an http.RoundTripper that records TCMD requests and responses in files of a directory,
+// or replays the recorded responses without sending requests to TCMD.
+// Recorded paths are relative to the client URL, so a cassette can be replayed with a different TCMD URL.
+// Auth headers are not recorded.
+type Cassette struct {
+	dir       string
+	base      string
+	transport http.RoundTripper
+	mu        sync.Mutex
+	recorded  []*interaction
+	used      []bool
+}
+
+// interaction is a request and its response saved in a cassette file
+type interaction struct {
+	Request  recordedMessage `json:"request"`
+	Response recordedMessage `json:"response"`
+	// Error of a request that did not receive a response, e.g., a timeout
+	Error string `json:"error,omitempty"`
+}
+
+// recordedMessage is a request or response. Body is set if the content is JSON, or Text otherwise.
+type recordedMessage struct {
+	Method string          `json:"method,omitempty"`
+	Path   string          `json:"path,omitempty"`
+	Query  string          `json:"query,omitempty"`
+	Status int             `json:"status,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	Text   string          `json:"text,omitempty"`
+}
+
+// RecordCassette returns a Cassette that sends requests to the specified transport, or http.DefaultTransport if it is nil,
+// and records them in an empty or new directory. baseURL is the URL of the client.
+func RecordCassette(dir, baseURL string, transport http.RoundTripper) (*Cassette, error) {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrapf(err, "Failed to create cassette directory %s", dir)
+	}
+	files, err := cassetteFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) > 0 {
+		return nil, errors.Errorf("cassette directory %s is not empty", dir)
+	}
+	return &Cassette{dir: dir, base: basePath(baseURL), transport: transport}, nil
+}
+
+// ReplayCassette returns a Cassette that replays responses recorded in a directory. baseURL is the URL of the client.
+func ReplayCassette(dir, baseURL string) (*Cassette, error) {
+	files, err := cassetteFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, errors.Errorf("No recorded request is found in cassette directory %s", dir)
+	}
+	c := &Cassette{dir: dir, base: basePath(baseURL)}
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to read cassette file %s", f)
+		}
+		var rec interaction
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return nil, errors.Wrapf(err, "Invalid cassette file %s", f)
+		}
+		c.recorded = append(c.recorded, &rec)
+	}
+	c.used = make([]bool, len(c.recorded))
+	return c, nil
+}
+
+// returns recorded files in a cassette directory in the order they were recorded
+func cassetteFiles(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to list cassette directory %s", dir)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// returns path of a URL without trailing /
+func basePath(baseURL string) string {
+	if u, err := url.Parse(baseURL); err == nil {
+		return strings.TrimSuffix(u.Path, "/")
+	}
+	return ""
+}
+
+// RoundTrip implements http.RoundTripper
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	request := recordedMessage{
+		Method: req.Method,
+		Path:   strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, c.base), "/"),
+		Query:  req.URL.RawQuery,
+	}
+	if req.Body != nil {
+		data, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		request.setContent(data)
+		req.Body = ioutil.NopCloser(bytes.NewReader(data))
+	}
+	if c.transport == nil {
+		return c.replay(req, request)
+	}
+	return c.record(req, request)
+}
+
+// send request to TCMD, and save the request and response in a new file of the cassette
+func (c *Cassette) record(req *http.Request, request recordedMessage) (*http.Response, error) {
+	rec := &interaction{Request: request}
+	resp, err := c.transport.RoundTrip(req)
+	if err != nil {
+		rec.Error = err.Error()
+	} else {
+		data, rerr := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if rerr != nil {
+			return nil, rerr
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+		rec.Response.Status = resp.StatusCode
+		rec.Response.setContent(data)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.recorded = append(c.recorded, rec)
+	kind, _ := parseObjectPath(req.URL.Path)
+	file := filepath.Join(c.dir, fmt.Sprintf("%04d-%s-%s.json", len(c.recorded), strings.ToLower(req.Method), kind))
+	data, merr := json.MarshalIndent(rec, "", "    ")
+	if merr != nil {
+		return nil, errors.Wrap(merr, "Failed to serialize recorded request")
+	}
+	if werr := ioutil.WriteFile(file, data, 0644); werr != nil {
+		return nil, errors.Wrapf(werr, "Failed to write cassette file %s", file)
+	}
+	return resp, err
+}
+
+// return response of the first unused recorded request of the same method, path, query and body
+func (c *Cassette) replay(req *http.Request, request recordedMessage) (*http.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, rec := range c.recorded {
+		if c.used[i] || !rec.Request.matches(request) {
+			continue
+		}
+		c.used[i] = true
+		if len(rec.Error) > 0 {
+			return nil, errors.New(rec.Error)
+		}
+		return response(req, rec.Response.Status, rec.Response.content()), nil
+	}
+	return nil, errors.Errorf("No recorded response for %s %s?%s in cassette %s", request.Method, request.Path, request.Query, c.dir)
+}
+
+// Unused returns the number of recorded requests that are not replayed yet
+func (c *Cassette) Unused() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, u := range c.used {
+		if !u {
+			n++
+		}
+	}
+	return n
+}
+
+// set JSON content as compact Body, or other content as Text
+func (m *recordedMessage) setContent(data []byte) {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err == nil && buf.Len() > 0 {
+		m.Body = json.RawMessage(buf.Bytes())
+	} else if len(data) > 0 {
+		m.Text = string(data)
+	}
+}
+
+func (m *recordedMessage) content() []byte {
+	if len(m.Body) > 0 {
+		return m.Body
+	}
+	return []byte(m.Text)
+}
+
+// returns true if a recorded request has the same method, path, query and content of another request
+func (m *recordedMessage) matches(other recordedMessage) bool {
+	if m.Method != other.Method || m.Path != other.Path || m.Query != other.Query || m.Text != other.Text {
+		return false
+	}
+	var a, b bytes.Buffer
+	json.Compact(&a, m.Body)
+	json.Compact(&b, other.Body)
+	return bytes.Equal(a.Bytes(), b.Bytes())
+}
@@ -0,0 +1,81 @@
+package tcmd
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCassetteRecordReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cassette")
+	assert.NoError(t, err, "temp dir should be created")
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/rest/asset":
+			var asset Asset
+			json.NewDecoder(r.Body).Decode(&asset)
+			asset.ID = 101
+			json.NewEncoder(w).Encode(asset)
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/asset":
+			w.Write([]byte("[]"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+		}
+	}))
+	ctx := context.Background()
+
+	rec, err := RecordCassette(dir, server.URL+"/rest", nil)
+	assert.NoError(t, err, "cassette should be created")
+	c := NewClient(server.URL+"/rest", WithBasicAuth("user", "secret"), WithHTTPClient(&http.Client{Transport: rec}), WithRetry(RetryPolicy{}))
+	created, err := c.CreateAsset(ctx, Asset{Name: "test-api", Label: "test-api", AssetType: "24"})
+	assert.NoError(t, err, "CreateAsset should not return error")
+	_, err = c.FindAssetByName(ctx, "unknown")
+	assert.NoError(t, err, "FindAssetByName should not return error")
+	_, err = c.GetAsset(ctx, 102)
+	assert.Error(t, err, "GetAsset should return error of status 404")
+	server.Close()
+	_, err = c.GetAsset(ctx, 101)
+	assert.Error(t, err, "GetAsset should return error of closed server")
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	assert.NoError(t, err, "cassette files should be listed")
+	if assert.Len(t, files, 4, "each request should be recorded") {
+		assert.Equal(t, "0001-post-asset.json", filepath.Base(files[0]), "file name should contain sequence, method and kind")
+		data, _ := ioutil.ReadFile(files[0])
+		assert.NotContains(t, string(data), "Basic", "auth header should not be recorded")
+		assert.Contains(t, string(data), `"path": "asset"`, "path should be relative to client URL")
+	}
+	_, err = RecordCassette(dir, server.URL, nil)
+	assert.Error(t, err, "cassette should not overwrite recorded requests")
+
+	// replay with a different TCMD URL
+	player, err := ReplayCassette(dir, "https://tcmd.example.com/rest/v1")
+	assert.NoError(t, err, "cassette should be loaded")
+	c = NewClient("https://tcmd.example.com/rest/v1", WithHTTPClient(&http.Client{Transport: player}), WithRetry(RetryPolicy{}))
+	replayed, err := c.CreateAsset(ctx, Asset{Name: "test-api", Label: "test-api", AssetType: "24"})
+	assert.NoError(t, err, "CreateAsset should be replayed")
+	assert.Equal(t, created.ID, replayed.ID, "replayed asset ID does not match")
+	missing, err := c.FindAssetByName(ctx, "unknown")
+	assert.NoError(t, err, "FindAssetByName should be replayed")
+	assert.Nil(t, missing, "unknown asset should not be found")
+	_, err = c.GetAsset(ctx, 102)
+	assert.Error(t, err, "status 404 should be replayed")
+	_, err = c.GetAsset(ctx, 101)
+	assert.Error(t, err, "transport error should be replayed")
+	assert.Equal(t, 0, player.Unused(), "all recorded requests should be replayed")
+
+	_, err = c.GetAsset(ctx, 101)
+	assert.Error(t, err, "request should not be replayed twice")
+	_, err = c.CreateAsset(ctx, Asset{Name: "other-api", Label: "other-api", AssetType: "24"})
+	assert.Error(t, err, "request of different content should not be replayed")
+}
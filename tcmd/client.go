@@ -0,0 +1,399 @@
+package tcmd
+
+/*
+Copyright © 2020 Yueming Xu <yxu@tibco.com>
+This file is subject to the license terms contained in the license file that is distributed with this file.
+*/
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultDataspace is the TCMD dataspace used when it is not configured
+	DefaultDataspace = "Tabula"
+	// DefaultDataset is the TCMD dataset used when it is not configured
+	DefaultDataset = "Tabula"
+	// DefaultTimeout is the timeout of a single TCMD REST call
+	DefaultTimeout = 5 * time.Second
+)
+
+// RetryPolicy configures retries of TCMD calls that fail with a network error, or status 429 or 5xx.
+// The delay before each retry is doubled from MinBackoff up to MaxBackoff with random jitter,
+// unless the server specifies a Retry-After delay, which is also limited by MaxBackoff.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt, or 0 to disable retries
+	MaxRetries int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy is used when the retry policy is not configured
+var DefaultRetryPolicy = RetryPolicy{MaxRetries: 3, MinBackoff: 500 * time.Millisecond, MaxBackoff: 30 * time.Second}
+
+// Client invokes TCMD REST APIs
+type Client struct {
+	url        string
+	authtoken  string
+	dataspace  string
+	dataset    string
+	httpClient *http.Client
+	timeout    time.Duration
+	retry      RetryPolicy
+	logf       func(format string, args ...interface{})
+}
+
+// Option configures optional parameters of a Client
+type Option func(*Client)
+
+// WithBasicAuth sets the TCMD technical user and password used for basic auth
+func WithBasicAuth(user, password string) Option {
+	return func(c *Client) {
+		if user != "" && password != "" {
+			c.authtoken = base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", user, password)))
+		}
+	}
+}
+
+// WithAuthToken sets the base64 encoded 'user:password' token used for basic auth
+func WithAuthToken(token string) Option {
+	return func(c *Client) {
+		c.authtoken = token
+	}
+}
+
+// WithDataset sets the TCMD dataspace and dataset that contain asset data types
+func WithDataset(dataspace, dataset string) Option {
+	return func(c *Client) {
+		if dataspace != "" {
+			c.dataspace = dataspace
+		}
+		if dataset != "" {
+			c.dataset = dataset
+		}
+	}
+}
+
+// WithHTTPClient replaces the default http client, e.g., to use a custom transport
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		if hc != nil {
+			c.httpClient = hc
+		}
+	}
+}
+
+// WithTimeout sets the timeout of a single TCMD REST call, which overrides the timeout of the http client
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithRetry sets the retry policy of failed TCMD REST calls
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithLogger sets a printf style function to trace REST calls
+func WithLogger(logf func(format string, args ...interface{})) Option {
+	return func(c *Client) {
+		c.logf = logf
+	}
+}
+
+// NewClient returns a client of TCMD REST API at the specified URL,
+// e.g., https://metadata.cloud.tibco.com/s/ienmnadebipc/ebx-ca-tabula/rest/v1
+func NewClient(url string, opts ...Option) *Client {
+	c := &Client{
+		url:        url,
+		dataspace:  DefaultDataspace,
+		dataset:    DefaultDataset,
+		httpClient: &http.Client{Timeout: DefaultTimeout},
+		retry:      DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.timeout > 0 {
+		hc := *c.httpClient
+		hc.Timeout = c.timeout
+		c.httpClient = &hc
+	}
+	return c
+}
+
+// URL returns the base URL of TCMD REST API
+func (c *Client) URL() string {
+	return c.url
+}
+
+// Dataspace returns the TCMD dataspace of asset data types
+func (c *Client) Dataspace() string {
+	return c.dataspace
+}
+
+// Dataset returns the TCMD dataset of asset data types
+func (c *Client) Dataset() string {
+	return c.dataset
+}
+
+func (c *Client) printf(format string, args ...interface{}) {
+	if c.logf != nil {
+		c.logf(format, args...)
+	}
+}
+
+// Get sends GET request to a path relative to the client URL, and returns the response body
+func (c *Client) Get(ctx context.Context, path string, params map[string]string) ([]byte, error) {
+	reqURL := fmt.Sprintf("%s/%s", c.url, path)
+	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to create GET request %s", reqURL)
+	}
+
+	if params != nil {
+		q := req.URL.Query()
+		for k, v := range params {
+			q.Add(k, v)
+		}
+		req.URL.RawQuery = q.Encode()
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, errors.Errorf("HTTP GET returned status %d", resp.StatusCode)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// Post sends JSON data to a path relative to the client URL, and returns the response body.
+// The request may have created an object when it fails with a network error or status 5xx,
+// so it is retried only after status 429, and never creates a duplicate.
+func (c *Client) Post(ctx context.Context, path string, data interface{}) ([]byte, error) {
+	return c.post(ctx, path, data, nil)
+}
+
+// post JSON data, and if the request is retried after an unknown result, call created to return the object
+// that may be created by the failed request, so the retry does not create a duplicate
+func (c *Client) post(ctx context.Context, path string, data interface{}, created func(context.Context) ([]byte, error)) ([]byte, error) {
+	reqURL := fmt.Sprintf("%s/%s", c.url, path)
+	jsonReq, err := json.Marshal(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to serialize request data")
+	}
+	c.printf("%s\n", jsonReq)
+
+	req, err := http.NewRequest(http.MethodPost, reqURL, bytes.NewBuffer(jsonReq))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to create POST request %s", reqURL)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := c.doWithGuard(ctx, req, created)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return nil, errors.Errorf("HTTP POST returned status %d %s", resp.StatusCode, body)
+	}
+	return body, err
+}
+
+// Put sends JSON data to a path relative to the client URL to update a resource, and returns the response body
+func (c *Client) Put(ctx context.Context, path string, data interface{}) ([]byte, error) {
+	reqURL := fmt.Sprintf("%s/%s", c.url, path)
+	jsonReq, err := json.Marshal(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to serialize request data")
+	}
+	c.printf("%s\n", jsonReq)
+
+	req, err := http.NewRequest(http.MethodPut, reqURL, bytes.NewBuffer(jsonReq))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to create PUT request %s", reqURL)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, errors.Errorf("HTTP PUT returned status %d", resp.StatusCode)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// Delete sends DELETE request to a path relative to the client URL, and returns the response body
+func (c *Client) Delete(ctx context.Context, path string) ([]byte, error) {
+	reqURL := fmt.Sprintf("%s/%s", c.url, path)
+	req, err := http.NewRequest(http.MethodDelete, reqURL, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to create DELETE request %s", reqURL)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, errors.Errorf("HTTP DELETE returned status %d", resp.StatusCode)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// send request with auth header, and trace the response status
+func (c *Client) do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	return c.doWithGuard(ctx, req, nil)
+}
+
+// send request, and retry it by the retry policy of the client. Before a POST request is retried after an unknown
+// result, i.e., a network error or status 5xx, call created to return the object if it is already created.
+// A POST request without created is not retried after an unknown result.
+func (c *Client) doWithGuard(ctx context.Context, req *http.Request, created func(context.Context) ([]byte, error)) (*http.Response, error) {
+	if c.authtoken != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Basic %s", c.authtoken))
+	}
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, errors.Wrapf(err, "Failed to reset body of %s %s", req.Method, req.URL)
+			}
+			req.Body = body
+		}
+		c.printf("%s %s\n", req.Method, req.URL)
+		resp, err := c.httpClient.Do(req.WithContext(ctx))
+		if err == nil {
+			c.printf("TCMD %s status: %d\n", req.Method, resp.StatusCode)
+			if attempt > 0 && req.Method == http.MethodDelete && resp.StatusCode == http.StatusNotFound {
+				// deleted by a previous attempt
+				resp.Body.Close()
+				return response(req, http.StatusOK, nil), nil
+			}
+		}
+		// a POST of unknown result is retried only if it can be checked by created
+		unknown := unknownResult(resp, err)
+		if attempt >= c.retry.MaxRetries || ctx.Err() != nil || !retryable(resp, err) ||
+			(unknown && req.Method == http.MethodPost && created == nil) {
+			if err != nil {
+				return nil, errors.Wrapf(err, "Failed http %s %s", req.Method, req.URL)
+			}
+			return resp, nil
+		}
+
+		delay := c.retryDelay(attempt, resp)
+		if resp != nil {
+			ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+		if err != nil {
+			c.printf("TCMD %s error: %v\n", req.Method, err)
+		}
+		c.printf("retry %s %s in %v\n", req.Method, req.URL, delay)
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrapf(ctx.Err(), "Failed http %s %s", req.Method, req.URL)
+		case <-time.After(delay):
+		}
+
+		if unknown && created != nil {
+			body, err := created(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if body != nil {
+				c.printf("%s %s is already created by a failed request\n", req.Method, req.URL)
+				return response(req, http.StatusOK, body), nil
+			}
+		}
+	}
+}
+
+// returns true if a request may succeed when it is sent again
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// returns true if a request failed with a network error or status 5xx, so it may or may not have been processed
+func unknownResult(resp *http.Response, err error) bool {
+	return err != nil || resp.StatusCode >= 500
+}
+
+// returns delay before a retry, which is doubled for each attempt with random jitter
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.retry.MinBackoff
+	for i := 0; i < attempt && delay < c.retry.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if c.retry.MaxBackoff > 0 && delay > c.retry.MaxBackoff {
+		delay = c.retry.MaxBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+	// wait at least half of the delay, so concurrent clients do not retry at the same time
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// returns delay before a retry of a failed attempt. A delay specified by the server is limited by MaxBackoff,
+// so a server that asks for a long delay does not block the client.
+func (c *Client) retryDelay(attempt int, resp *http.Response) time.Duration {
+	if resp == nil {
+		return c.backoff(attempt)
+	}
+	d, ok := retryAfter(resp)
+	if !ok {
+		return c.backoff(attempt)
+	}
+	if c.retry.MaxBackoff > 0 && d > c.retry.MaxBackoff {
+		c.printf("Retry-After %v is limited to %v\n", d, c.retry.MaxBackoff)
+		return c.retry.MaxBackoff
+	}
+	return d
+}
+
+// returns delay specified by Retry-After header in seconds or as an HTTP date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if len(value) == 0 {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
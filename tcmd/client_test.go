@@ -0,0 +1,363 @@
+package tcmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientAssets(t *testing.T) {
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/rest/asset":
+			var asset Asset
+			json.NewDecoder(r.Body).Decode(&asset)
+			asset.ID = 101
+			json.NewEncoder(w).Encode(asset)
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/asset/101":
+			json.NewEncoder(w).Encode(Asset{ID: 101, Name: "test-api", Label: "test-api"})
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/asset":
+			switch r.URL.Query().Get("predicate") {
+			case "name='test-api'":
+				json.NewEncoder(w).Encode([]Asset{{ID: 101, Name: "test-api"}})
+			case "parent='101'":
+				json.NewEncoder(w).Encode([]Asset{{ID: 102, Name: "info", Parent: "101"}})
+			case "parent='101' and name='user''s'":
+				json.NewEncoder(w).Encode([]Asset{{ID: 103, Name: "user's", Parent: "101"}})
+			default:
+				w.Write([]byte("[]"))
+			}
+		case r.Method == http.MethodDelete && r.URL.Path == "/rest/asset/101":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	c := NewClient(server.URL+"/rest", WithBasicAuth("user", "secret"))
+
+	created, err := c.CreateAsset(ctx, Asset{Name: "test-api", Label: "test-api", AssetType: "24"})
+	assert.NoError(t, err, "CreateAsset should not return error")
+	assert.Equal(t, 101, created.ID, "created asset ID does not match")
+	assert.Equal(t, "Basic dXNlcjpzZWNyZXQ=", auth, "basic auth header does not match")
+
+	asset, err := c.GetAsset(ctx, 101)
+	assert.NoError(t, err, "GetAsset should not return error")
+	assert.Equal(t, "test-api", asset.Label, "asset label does not match")
+
+	found, err := c.FindAssetByName(ctx, "test-api")
+	assert.NoError(t, err, "FindAssetByName should not return error")
+	assert.Equal(t, 101, found.ID, "found asset ID does not match")
+
+	missing, err := c.FindAssetByName(ctx, "unknown")
+	assert.NoError(t, err, "FindAssetByName should not return error for unknown asset")
+	assert.Nil(t, missing, "unknown asset should not be found")
+
+	quoted, err := c.FindChildAsset(ctx, 101, "user's")
+	assert.NoError(t, err, "FindChildAsset should not return error for name with quote")
+	if assert.NotNil(t, quoted, "asset of name with quote should be found") {
+		assert.Equal(t, 103, quoted.ID, "found asset ID does not match")
+	}
+
+	children, err := c.ListChildren(ctx, 101)
+	assert.NoError(t, err, "ListChildren should not return error")
+	assert.Equal(t, 1, len(children), "number of children does not match")
+
+	assert.NoError(t, c.DeleteAsset(ctx, 101), "DeleteAsset should not return error")
+	assert.Error(t, c.DeleteAsset(ctx, 999), "DeleteAsset of unknown asset should return error")
+}
+
+func TestClientDataTypes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/rest/Space/Set/datatype":
+			var dt DataType
+			json.NewDecoder(r.Body).Decode(&dt)
+			dt.ID = 1001
+			json.NewEncoder(w).Encode(dt)
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/Space/Set/datatype":
+			if r.URL.Query().Get("predicate") == "name='string'" {
+				json.NewEncoder(w).Encode([]DataType{{ID: 1000, Name: "string", Label: "string"}})
+				return
+			}
+			w.Write([]byte("[]"))
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/Space/Set/datatype/1000":
+			json.NewEncoder(w).Encode(DataType{ID: 1000, Name: "string", Label: "string"})
+		case r.Method == http.MethodDelete && r.URL.Path == "/rest/Space/Set/datatype/1001":
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	c := NewClient(server.URL+"/rest", WithDataset("Space", "Set"))
+
+	dt, err := c.FindDataTypeByName(ctx, "string")
+	assert.NoError(t, err, "FindDataTypeByName should not return error")
+	assert.Equal(t, 1000, dt.ID, "data type ID does not match")
+
+	missing, err := c.FindDataTypeByName(ctx, "#/components/schemas/unknown")
+	assert.NoError(t, err, "FindDataTypeByName should not return error for unknown type")
+	assert.Nil(t, missing, "unknown data type should not be found")
+
+	dt, err = c.GetDataType(ctx, 1000)
+	assert.NoError(t, err, "GetDataType should not return error")
+	assert.Equal(t, "string", dt.Label, "data type label does not match")
+
+	created, err := c.CreateDataType(ctx, DataType{Name: "#/components/schemas/test", Label: "#/components/schemas/test", ComplexType: true})
+	assert.NoError(t, err, "CreateDataType should not return error")
+	assert.Equal(t, 1001, created.ID, "created data type ID does not match")
+
+	assert.NoError(t, c.DeleteDataType(ctx, 1001), "DeleteDataType should not return error")
+}
+
+func TestClientUpsertAssets(t *testing.T) {
+	var updated Asset
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/asset":
+			switch r.URL.Query().Get("predicate") {
+			case "name='streetlights'":
+				json.NewEncoder(w).Encode([]Asset{{ID: 102, Name: "streetlights", Parent: "101"}, {ID: 101, Name: "streetlights"}})
+			case "parent='101' and name='info'":
+				json.NewEncoder(w).Encode([]Asset{{ID: 103, Name: "info", Parent: "101"}})
+			default:
+				w.Write([]byte("[]"))
+			}
+		case r.Method == http.MethodPut && r.URL.Path == "/rest/asset/103":
+			json.NewDecoder(r.Body).Decode(&updated)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	c := NewClient(server.URL + "/rest")
+
+	rootAsset, err := c.FindChildAsset(ctx, 0, "streetlights")
+	assert.NoError(t, err, "FindChildAsset should not return error for root asset")
+	assert.Equal(t, 101, rootAsset.ID, "root asset should not have parent")
+
+	info, err := c.FindChildAsset(ctx, 101, "info")
+	assert.NoError(t, err, "FindChildAsset should not return error")
+	assert.Equal(t, 103, info.ID, "child asset ID does not match")
+
+	missing, err := c.FindChildAsset(ctx, 101, "servers")
+	assert.NoError(t, err, "FindChildAsset should not return error for unknown child")
+	assert.Nil(t, missing, "unknown child should not be found")
+
+	info.Description = "updated"
+	result, err := c.UpdateAsset(ctx, *info)
+	assert.NoError(t, err, "UpdateAsset should not return error")
+	assert.Equal(t, "updated", result.Description, "updated description does not match")
+	assert.Equal(t, "updated", updated.Description, "PUT request does not contain updated description")
+
+	_, err = c.UpdateAsset(ctx, Asset{Name: "new"})
+	assert.Error(t, err, "UpdateAsset should return error for asset without ID")
+}
+
+func TestClientPostErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/rest/asset":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"invalid asset type"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/asset":
+			w.Write([]byte("[]"))
+		case r.Method == http.MethodPost && r.URL.Path == "/rest/Tabula/Tabula/datatype":
+			// server accepts the request but does not return the created data type
+			w.Write([]byte("{}"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	c := NewClient(server.URL + "/rest")
+
+	_, err := c.CreateAsset(ctx, Asset{Name: "test-api", Label: "test-api", AssetType: "99"})
+	assert.Error(t, err, "CreateAsset should return error for HTTP status 400")
+	assert.Contains(t, err.Error(), "invalid asset type", "error should contain response body")
+
+	_, err = c.CreateDataType(ctx, DataType{Name: "string", Label: "string"})
+	assert.Error(t, err, "CreateDataType should return error if no ID is returned")
+}
+
+func TestClientRetry(t *testing.T) {
+	// handlers of timed out requests may still run when the requests are retried
+	var posts, gets, deletes int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/rest/asset":
+			atomic.AddInt32(&posts, 1)
+			// the asset is created, but the gateway fails to return the response
+			w.WriteHeader(http.StatusBadGateway)
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/asset":
+			if atomic.AddInt32(&gets, 1) == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			if atomic.LoadInt32(&posts) == 0 {
+				w.Write([]byte("[]"))
+				return
+			}
+			json.NewEncoder(w).Encode([]Asset{{ID: 101, Name: "test-api", Label: "test-api", AssetType: "24"}})
+		case r.Method == http.MethodDelete && r.URL.Path == "/rest/asset/101":
+			if atomic.AddInt32(&deletes, 1) == 1 {
+				// the asset is deleted, but the request times out
+				time.Sleep(200 * time.Millisecond)
+			}
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/asset/102":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	policy := RetryPolicy{MaxRetries: 2, MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+	c := NewClient(server.URL+"/rest", WithRetry(policy), WithTimeout(100*time.Millisecond))
+
+	created, err := c.CreateAsset(ctx, Asset{Name: "test-api", Label: "test-api", AssetType: "24"})
+	assert.NoError(t, err, "CreateAsset should be retried")
+	assert.Equal(t, 101, created.ID, "asset created by the failed request should be returned")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&posts), "POST should not be sent again if the asset is created")
+	assert.Equal(t, int32(3), atomic.LoadInt32(&gets), "query should be retried after status 429")
+
+	assert.NoError(t, c.DeleteAsset(ctx, 101), "asset deleted by a timed out request should not return error")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&deletes), "DELETE should be retried after timeout")
+
+	_, err = c.GetAsset(ctx, 102)
+	assert.Error(t, err, "GetAsset should return error after max retries")
+
+	_, err = c.CreateAsset(ctx, Asset{Name: "test-api", Label: "other", AssetType: "24"})
+	assert.Error(t, err, "asset of different content should not be returned")
+	assert.Equal(t, int32(4), atomic.LoadInt32(&posts), "POST should be retried if the asset is not created")
+
+	_, err = c.CreateAsset(ctx, Asset{Name: "test-api", Label: "test-api", AssetType: "24"})
+	assert.Error(t, err, "asset that exists before the request should not be returned as created")
+	assert.Equal(t, int32(7), atomic.LoadInt32(&posts), "POST should be retried if only an existing asset is found")
+}
+
+func TestUnguardedPostRetry(t *testing.T) {
+	var posts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&posts, 1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		// the object may be created, but the gateway fails to return the response
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	policy := RetryPolicy{MaxRetries: 3, MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+	c := NewClient(server.URL+"/rest", WithRetry(policy))
+	_, err := c.Post(context.Background(), "asset", Asset{Name: "test-api"})
+	assert.Error(t, err, "Post should return error of unknown result")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&posts), "Post should be retried after status 429, but not after status 5xx")
+}
+
+func TestRetryDelay(t *testing.T) {
+	c := NewClient("", WithRetry(RetryPolicy{MaxRetries: 5, MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}))
+	for attempt, max := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		d := c.backoff(attempt)
+		assert.True(t, d >= max*time.Millisecond/2 && d <= max*time.Millisecond, "backoff %v of attempt %d should be jittered below %dms", d, attempt, max)
+	}
+
+	resp := &http.Response{Header: http.Header{}}
+	_, ok := retryAfter(resp)
+	assert.False(t, ok, "Retry-After should not be found")
+	resp.Header.Set("Retry-After", "3")
+	d, ok := retryAfter(resp)
+	assert.True(t, ok, "Retry-After should be parsed")
+	assert.Equal(t, 3*time.Second, d, "Retry-After seconds do not match")
+	resp.Header.Set("Retry-After", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	d, ok = retryAfter(resp)
+	assert.True(t, ok && d > 58*time.Second && d <= time.Minute, "Retry-After date should be parsed, got %v", d)
+
+	resp.Header.Set("Retry-After", "86400")
+	assert.Equal(t, time.Second, c.retryDelay(0, resp), "Retry-After should be limited by max backoff")
+	resp.Header.Set("Retry-After", "0")
+	assert.Equal(t, time.Duration(0), c.retryDelay(3, resp), "Retry-After should replace backoff")
+}
+
+func TestRecorderDryRun(t *testing.T) {
+	var updates int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/asset/101":
+			json.NewEncoder(w).Encode(Asset{ID: 101, Name: "old-api"})
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/asset":
+			if r.URL.Query().Get("predicate") == "name='sensor-900000001'" {
+				json.NewEncoder(w).Encode([]Asset{{ID: 102, Name: "sensor-900000001"}})
+				return
+			}
+			w.Write([]byte("[]"))
+		default:
+			updates++
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	recorder := NewRecorder(nil)
+	c := NewClient(server.URL+"/rest", WithHTTPClient(&http.Client{Transport: recorder}))
+
+	dt, err := c.CreateDataType(ctx, DataType{Name: "string", Label: "string"})
+	assert.NoError(t, err, "CreateDataType should not return error in dry-run")
+	assert.True(t, IsPlaceholder(dt.ID), "created data type should have placeholder ID")
+
+	parent, err := c.CreateAsset(ctx, Asset{Name: "test-api", AssetDataType: "1"})
+	assert.NoError(t, err, "CreateAsset should not return error in dry-run")
+	assert.True(t, IsPlaceholder(parent.ID), "created asset should have placeholder ID")
+
+	child, err := c.CreateAsset(ctx, Asset{Name: "info", Parent: "900000001"})
+	assert.NoError(t, err, "CreateAsset should not return error in dry-run")
+	assert.Equal(t, parent.ID+1, child.ID, "placeholder IDs should be sequential")
+
+	children, err := c.ListChildren(ctx, parent.ID)
+	assert.NoError(t, err, "ListChildren of new asset should not return error")
+	assert.Nil(t, children, "new asset should not have children")
+
+	found, err := c.FindAssetByName(ctx, "sensor-900000001")
+	assert.NoError(t, err, "FindAssetByName should not return error in dry-run")
+	if assert.NotNil(t, found, "name like a placeholder ID should be queried in TCMD") {
+		assert.Equal(t, 102, found.ID, "found asset ID does not match")
+	}
+
+	assert.NoError(t, c.DeleteAsset(ctx, 101), "DeleteAsset should not return error in dry-run")
+	_, err = c.Put(ctx, c.dataTypePath()+"/1000", DataType{ID: 1000, Name: "number", Label: "number"})
+	assert.NoError(t, err, "PUT data type should not return error in dry-run")
+	assert.Equal(t, 0, updates, "dry-run should not send updates to TCMD")
+
+	requests := recorder.Requests()
+	assert.Equal(t, 5, len(requests), "number of recorded requests does not match")
+	assert.Equal(t, "old-api", requests[3].Name, "name of deleted asset does not match")
+
+	var plan strings.Builder
+	recorder.WritePlan(&plan)
+	assert.Contains(t, plan.String(), "create asset <new-2> test-api\n  create asset <new-3> info\n", "plan should print new asset tree")
+	assert.Contains(t, plan.String(), "delete asset 101 old-api", "plan should print deleted asset")
+	assert.Contains(t, plan.String(), "update datatype 1000 number", "plan should print updated data type")
+}
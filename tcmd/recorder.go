@@ -0,0 +1,304 @@
+package tcmd
+
+/*
+Copyright © 2020 Yueming Xu <yxu@tibco.com>
+This file is subject to the license terms contained in the license file that is distributed with this file.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PlaceholderBase is the first ID assigned by Recorder to assets and data types that are not created yet
+const PlaceholderBase = 900000000
+
+// conditionPattern matches each condition of a query predicate, e.g., parent='12', where quotes in a value are doubled
+var conditionPattern = regexp.MustCompile(`(\w+)='((?:[^']|'')*)'`)
+
+// IsPlaceholder returns true if an ID is assigned by Recorder for an asset or data type not created yet
+func IsPlaceholder(id int) bool {
+	return id >= PlaceholderBase
+}
+
+// RecordedRequest is a request intercepted by Recorder
+type RecordedRequest struct {
+	Method string
+	// Kind is either asset or datatype
+	Kind string
+	// ID of the created, updated or deleted object
+	ID       int
+	Asset    *Asset
+	DataType *DataType
+	// Name of deleted object if it can be fetched from TCMD
+	Name string
+}
+
+// Recorder is an http.RoundTripper used for dry-run. It forwards GET requests to TCMD,
+// and records POST, PUT and DELETE requests without sending them. Created objects are
+// assigned placeholder IDs, so the caller can continue to create their children.
+type Recorder struct {
+	transport http.RoundTripper
+	mu        sync.Mutex
+	nextID    int
+	requests  []RecordedRequest
+	assets    map[int]*Asset
+	dataTypes map[int]*DataType
+}
+
+// NewRecorder returns a Recorder that forwards GET requests to the specified transport,
+// or http.DefaultTransport if it is nil
+func NewRecorder(transport http.RoundTripper) *Recorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &Recorder{
+		transport: transport,
+		nextID:    PlaceholderBase,
+		assets:    make(map[int]*Asset),
+		dataTypes: make(map[int]*DataType),
+	}
+}
+
+// Requests returns the recorded requests in the order they were sent
+func (r *Recorder) Requests() []RecordedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := make([]RecordedRequest, len(r.requests))
+	copy(result, r.requests)
+	return result
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	kind, id := parseObjectPath(req.URL.Path)
+	if req.Method == http.MethodGet {
+		return r.get(req, kind, id)
+	}
+
+	var body []byte
+	if req.Body != nil {
+		data, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = data
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec := RecordedRequest{Method: req.Method, Kind: kind, ID: id}
+	var result interface{}
+	switch req.Method {
+	case http.MethodPost, http.MethodPut:
+		if kind == "datatype" {
+			var dt DataType
+			if err := json.Unmarshal(body, &dt); err != nil {
+				return nil, err
+			}
+			if req.Method == http.MethodPost {
+				dt.ID = r.nextID
+				r.nextID++
+			}
+			rec.ID, rec.DataType = dt.ID, &dt
+			r.dataTypes[dt.ID] = &dt
+			result = dt
+		} else {
+			var asset Asset
+			if err := json.Unmarshal(body, &asset); err != nil {
+				return nil, err
+			}
+			if req.Method == http.MethodPost {
+				asset.ID = r.nextID
+				r.nextID++
+			}
+			rec.ID, rec.Asset = asset.ID, &asset
+			r.assets[asset.ID] = &asset
+			result = asset
+		}
+	case http.MethodDelete:
+		rec.Name = r.fetchName(req, kind, id)
+	}
+	r.requests = append(r.requests, rec)
+	return jsonResponse(req, http.StatusOK, result)
+}
+
+// forward GET request to TCMD unless it queries objects not created yet
+func (r *Recorder) get(req *http.Request, kind string, id int) (*http.Response, error) {
+	r.mu.Lock()
+	if IsPlaceholder(id) {
+		defer r.mu.Unlock()
+		if kind == "datatype" {
+			if dt, ok := r.dataTypes[id]; ok {
+				return jsonResponse(req, http.StatusOK, dt)
+			}
+		} else if asset, ok := r.assets[id]; ok {
+			return jsonResponse(req, http.StatusOK, asset)
+		}
+		return jsonResponse(req, http.StatusNotFound, nil)
+	}
+	r.mu.Unlock()
+
+	for _, m := range conditionPattern.FindAllStringSubmatch(req.URL.Query().Get("predicate"), -1) {
+		if m[1] != "parent" && m[1] != "id" && m[1] != "assetDataType" {
+			// names may contain digits of a placeholder
+			continue
+		}
+		if n, err := strconv.Atoi(m[2]); err == nil && IsPlaceholder(n) {
+			// a new object does not have any children or typed assets in TCMD
+			return jsonResponse(req, http.StatusOK, []interface{}{})
+		}
+	}
+	return r.transport.RoundTrip(req)
+}
+
+// fetch name of an existing object for reporting, and ignore any error
+func (r *Recorder) fetchName(req *http.Request, kind string, id int) string {
+	if IsPlaceholder(id) {
+		if kind == "datatype" {
+			if dt, ok := r.dataTypes[id]; ok {
+				return dt.Name
+			}
+		} else if asset, ok := r.assets[id]; ok {
+			return asset.Name
+		}
+		return ""
+	}
+	get, err := http.NewRequest(http.MethodGet, req.URL.String(), nil)
+	if err != nil {
+		return ""
+	}
+	get.Header = req.Header.Clone()
+	resp, err := r.transport.RoundTrip(get.WithContext(req.Context()))
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	var obj struct {
+		Name string `json:"name"`
+	}
+	if resp.StatusCode == http.StatusOK {
+		json.NewDecoder(resp.Body).Decode(&obj)
+	}
+	return obj.Name
+}
+
+// returns object kind, i.e., asset or datatype, and object ID from a request path
+func parseObjectPath(path string) (string, int) {
+	tokens := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	kind := "asset"
+	for _, t := range tokens {
+		if t == "datatype" {
+			kind = "datatype"
+		}
+	}
+	if id, err := strconv.Atoi(tokens[len(tokens)-1]); err == nil {
+		return kind, id
+	}
+	return kind, 0
+}
+
+func jsonResponse(req *http.Request, status int, data interface{}) (*http.Response, error) {
+	var body []byte
+	if data != nil {
+		var err error
+		if body, err = json.Marshal(data); err != nil {
+			return nil, err
+		}
+	}
+	return response(req, status, body), nil
+}
+
+// returns response of a request with JSON body
+func response(req *http.Request, status int, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// placeholder or real ID for display
+func displayID(id int) string {
+	if IsPlaceholder(id) {
+		return fmt.Sprintf("<new-%d>", id-PlaceholderBase+1)
+	}
+	return strconv.Itoa(id)
+}
+
+// WritePlan prints the recorded data types to create, the planned asset tree, and updates and deletions
+func (r *Recorder) WritePlan(w io.Writer) {
+	requests := r.Requests()
+	fmt.Fprintln(w, "dry-run plan:")
+
+	for _, rec := range requests {
+		if rec.Method == http.MethodPost && rec.Kind == "datatype" {
+			fmt.Fprintf(w, "create data type %s %s\n", displayID(rec.ID), rec.DataType.Name)
+		}
+	}
+
+	// index new assets by parent, so they can be printed as trees
+	children := make(map[string][]*Asset)
+	var tops []*Asset
+	for _, rec := range requests {
+		if rec.Method == http.MethodPost && rec.Kind == "asset" {
+			pid, err := strconv.Atoi(rec.Asset.Parent)
+			if err == nil && IsPlaceholder(pid) {
+				children[rec.Asset.Parent] = append(children[rec.Asset.Parent], rec.Asset)
+			} else {
+				tops = append(tops, rec.Asset)
+			}
+		}
+	}
+	var printTree func(a *Asset, depth int)
+	printTree = func(a *Asset, depth int) {
+		fmt.Fprintf(w, "%screate asset %s %s\n", strings.Repeat("  ", depth), displayID(a.ID), a.Name)
+		for _, c := range children[strconv.Itoa(a.ID)] {
+			printTree(c, depth+1)
+		}
+	}
+	for _, a := range tops {
+		if len(a.Parent) > 0 {
+			fmt.Fprintf(w, "under asset %s:\n", a.Parent)
+			printTree(a, 1)
+		} else {
+			printTree(a, 0)
+		}
+	}
+
+	creates, updates, deletes := 0, 0, 0
+	for _, rec := range requests {
+		switch rec.Method {
+		case http.MethodPost:
+			creates++
+		case http.MethodPut:
+			updates++
+			var name string
+			if rec.Kind == "datatype" {
+				name = rec.DataType.Name
+			} else {
+				name = rec.Asset.Name
+			}
+			fmt.Fprintf(w, "update %s %s %s\n", rec.Kind, displayID(rec.ID), name)
+		case http.MethodDelete:
+			deletes++
+			fmt.Fprintf(w, "delete %s %s %s\n", rec.Kind, displayID(rec.ID), rec.Name)
+		}
+	}
+	fmt.Fprintf(w, "dry-run total: %d create, %d update, %d delete\n", creates, updates, deletes)
+}